@@ -7,7 +7,7 @@ import (
 
 type AstPrinter struct {
 	depth int
-	env   Environment
+	env   *Environment
 }
 
 func NewAstPrinter(existingEnv *map[string]any) AstPrinter {
@@ -17,7 +17,7 @@ func NewAstPrinter(existingEnv *map[string]any) AstPrinter {
 	} else {
 		env = *existingEnv
 	}
-	return AstPrinter{depth: 0, env: Environment{name: "ASTENV", values: env}}
+	return AstPrinter{depth: 0, env: &Environment{name: "ASTENV", values: env}}
 }
 
 func (a AstPrinter) print(stmts []Statement) error {
@@ -45,8 +45,13 @@ func (a AstPrinter) visitVarDeclarationStatement(stmt VarDeclarationStatement) (
 		}
 	}
 
+	kind := "VarDeclarationStatement"
+	if stmt.constant {
+		kind = "ConstDeclarationStatement"
+	}
 	out := fmt.Sprintf(
-		"VarDeclarationStatement: \n%sName: %s\n%sValue: %s",
+		"%s: \n%sName: %s\n%sValue: %s",
+		kind,
 		strings.Repeat("\t", a.depth),
 		stmt.name,
 		strings.Repeat("\t", a.depth),
@@ -65,8 +70,7 @@ func (a AstPrinter) visitBlockStatement(stmt BlockStatement) (any, error) {
 	out := "BlockStatement: "
 
 	oldEnv := a.env
-	newEnv := Environment{name: fmt.Sprintf("ASTENV%d", a.depth), values: make(map[string]any), parent: &oldEnv}
-	a.env = newEnv
+	a.env = NewEnvironment(fmt.Sprintf("ASTENV%d", a.depth), oldEnv)
 
 	for _, statement := range stmt.stmts {
 		val, err := statement.accept(a)
@@ -82,6 +86,251 @@ func (a AstPrinter) visitBlockStatement(stmt BlockStatement) (any, error) {
 	return out, nil
 }
 
+func (a AstPrinter) visitIfStatement(stmt IfStatement) (any, error) {
+	a.depth++
+	condition, err := stmt.condition.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	thenBranch, err := stmt.thenBranch.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("IfStatement: %s", condition) + fmt.Sprintf(
+		"\n%sThen -> %s", strings.Repeat("\t", a.depth), thenBranch)
+
+	if stmt.elseBranch != nil {
+		elseBranch, err := stmt.elseBranch.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sElse -> %s", strings.Repeat("\t", a.depth), elseBranch)
+	}
+
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitWhileStatement(stmt WhileStatement) (any, error) {
+	a.depth++
+	condition, err := stmt.condition.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := stmt.body.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("WhileStatement: %s%s", loopLabel(stmt.label), condition) + fmt.Sprintf(
+		"\n%sBody -> %s", strings.Repeat("\t", a.depth), body)
+
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitForStatement(stmt ForStatement) (any, error) {
+	a.depth++
+	out := "ForStatement: " + loopLabel(stmt.label)
+
+	oldEnv := a.env
+	a.env = NewEnvironment(fmt.Sprintf("ASTENV%d", a.depth), oldEnv)
+
+	if stmt.initializer != nil {
+		initializer, err := stmt.initializer.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sInitializer -> %s", strings.Repeat("\t", a.depth), initializer)
+	}
+
+	if stmt.condition != nil {
+		condition, err := stmt.condition.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sCondition -> %s", strings.Repeat("\t", a.depth), condition)
+	}
+
+	if stmt.increment != nil {
+		increment, err := stmt.increment.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sIncrement -> %s", strings.Repeat("\t", a.depth), increment)
+	}
+
+	body, err := stmt.body.accept(a)
+	if err != nil {
+		return "", err
+	}
+	out += fmt.Sprintf("\n%sBody -> %s", strings.Repeat("\t", a.depth), body)
+
+	a.env = oldEnv
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitBreakStatement(stmt BreakStatement) (any, error) {
+	return "BreakStatement" + jumpLabel(stmt.label), nil
+}
+
+func (a AstPrinter) visitContinueStatement(stmt ContinueStatement) (any, error) {
+	return "ContinueStatement" + jumpLabel(stmt.label), nil
+}
+
+func loopLabel(label *Token) string {
+	if label == nil {
+		return ""
+	}
+	return fmt.Sprintf("(label: %s) ", label.lexeme)
+}
+
+func jumpLabel(label *Token) string {
+	if label == nil {
+		return ""
+	}
+	return fmt.Sprintf(": %s", label.lexeme)
+}
+
+func (a AstPrinter) visitFunctionStatement(stmt FunctionStatement) (any, error) {
+	a.depth++
+	a.env.define(stmt.name.lexeme, fmt.Sprintf("<fn %s>", stmt.name.lexeme))
+
+	var params []string
+	for _, param := range stmt.params {
+		params = append(params, param.lexeme)
+	}
+	out := fmt.Sprintf("FunctionStatement: %s(%s)", stmt.name.lexeme, strings.Join(params, ", "))
+
+	oldEnv := a.env
+	a.env = NewEnvironment(fmt.Sprintf("ASTENV%d", a.depth), oldEnv)
+
+	for _, statement := range stmt.body {
+		val, err := statement.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%s -> %s", strings.Repeat("\t", a.depth), val)
+	}
+
+	a.env = oldEnv
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitClassStatement(stmt ClassStatement) (any, error) {
+	a.depth++
+	a.env.define(stmt.name.lexeme, stmt.name.lexeme)
+
+	out := fmt.Sprintf("ClassStatement: %s", stmt.name.lexeme)
+	if stmt.superclass != nil {
+		out += fmt.Sprintf(" < %s", stmt.superclass.name.lexeme)
+	}
+
+	// Methods are printed in their own scope so they don't leak into the
+	// enclosing one as functions.
+	oldEnv := a.env
+	a.env = NewEnvironment(fmt.Sprintf("ASTENV%d", a.depth), oldEnv)
+
+	for _, method := range stmt.methods {
+		val, err := method.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%s -> %s", strings.Repeat("\t", a.depth), val)
+	}
+
+	a.env = oldEnv
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitReturnStatement(stmt ReturnStatement) (any, error) {
+	if stmt.value == nil {
+		return "ReturnStatement", nil
+	}
+
+	a.depth++
+	value, err := stmt.value.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("ReturnStatement: \n%s%s", strings.Repeat("\t", a.depth), value)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitImportStatement(stmt ImportStatement) (any, error) {
+	out := fmt.Sprintf("ImportStatement: %s", stmt.path.lexeme)
+	if stmt.alias != nil {
+		out += fmt.Sprintf(" as %s", stmt.alias.lexeme)
+	}
+	if len(stmt.names) > 0 {
+		names := make([]string, len(stmt.names))
+		for i, name := range stmt.names {
+			names[i] = name.lexeme
+		}
+		out += fmt.Sprintf(" names {%s}", strings.Join(names, ", "))
+	}
+	return out, nil
+}
+
+func (a AstPrinter) visitExportStatement(stmt ExportStatement) (any, error) {
+	a.depth++
+	declaration, err := stmt.declaration.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("ExportStatement: \n%s%s", strings.Repeat("\t", a.depth), declaration)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitThrowStatement(stmt ThrowStatement) (any, error) {
+	a.depth++
+	value, err := stmt.value.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("ThrowStatement: \n%s%s", strings.Repeat("\t", a.depth), value)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitTryStatement(stmt TryStatement) (any, error) {
+	a.depth++
+	body, err := stmt.body.accept(a)
+	if err != nil {
+		return "", err
+	}
+	out := "TryStatement: " + fmt.Sprintf("\n%sBody    -> %s", strings.Repeat("\t", a.depth), body)
+
+	if stmt.catchBody != nil {
+		catchBody, err := stmt.catchBody.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sCatch %s -> %s", strings.Repeat("\t", a.depth), stmt.catchName.lexeme, catchBody)
+	}
+	if stmt.finallyBody != nil {
+		finallyBody, err := stmt.finallyBody.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sFinally -> %s", strings.Repeat("\t", a.depth), finallyBody)
+	}
+
+	a.depth--
+	return out, nil
+}
+
 func (a AstPrinter) visitExpressionStatemet(stmt ExpressionStatement) (any, error) {
 	a.depth++
 	expr, err := stmt.expr.accept(a)
@@ -121,10 +370,42 @@ func (a AstPrinter) visitAssign(expr Assign) (any, error) {
 
 }
 
+func (a AstPrinter) visitCompoundAssign(expr CompoundAssign) (any, error) {
+	a.depth++
+
+	target, err := expr.target.accept(a)
+	if err != nil {
+		return "", err
+	}
+	value, err := expr.value.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("CompoundAssign: %s", expr.operator.lexeme) + fmt.Sprintf(
+		"\n%sTarget -> %s", strings.Repeat("\t", a.depth), target) + fmt.Sprintf(
+		"\n%sValue  -> %s", strings.Repeat("\t", a.depth), value)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitIncrement(expr Increment) (any, error) {
+	target, err := expr.target.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	if expr.prefix {
+		return fmt.Sprintf("Increment: %s(%s)", expr.operator.lexeme, target), nil
+	}
+	return fmt.Sprintf("Increment: (%s)%s", target, expr.operator.lexeme), nil
+}
+
 func (a AstPrinter) visitVariable(expr Variable) (any, error) {
+	// Parameters and names declared later in the program have no value yet.
 	value, err := a.env.get(expr.name.lexeme)
 	if err != nil {
-		return "", err
+		return fmt.Sprintf("Variable: %s", expr.name.lexeme), nil
 	}
 
 	if str_value, ok := value.(string); ok {
@@ -181,6 +462,260 @@ func (a AstPrinter) visitBinary(expr Binary) (any, error) {
 	return out, nil
 }
 
+func (a AstPrinter) visitLogical(expr Logical) (any, error) {
+	a.depth++
+
+	left, err := expr.left.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	right, err := expr.right.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("Logical: %s", expr.operator.lexeme) + fmt.Sprintf(
+		"\n%sLeft  -> %s", strings.Repeat("\t", a.depth), left) + fmt.Sprintf(
+		"\n%sRight -> %s", strings.Repeat("\t", a.depth), right)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitCall(expr Call) (any, error) {
+	a.depth++
+
+	callee, err := expr.callee.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("Call: %s", callee)
+	for _, argument := range expr.arguments {
+		value, err := argument.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sArgument -> %s", strings.Repeat("\t", a.depth), value)
+	}
+
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitGet(expr Get) (any, error) {
+	object, err := expr.object.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	kind := "Get"
+	if expr.optional {
+		kind = "OptionalGet"
+	}
+	return fmt.Sprintf("%s: %s Object -> %s", kind, expr.name.lexeme, object), nil
+}
+
+func (a AstPrinter) visitSet(expr Set) (any, error) {
+	a.depth++
+
+	object, err := expr.object.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := expr.value.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf("Set: %s", expr.name.lexeme) + fmt.Sprintf(
+		"\n%sObject -> %s", strings.Repeat("\t", a.depth), object) + fmt.Sprintf(
+		"\n%sValue  -> %s", strings.Repeat("\t", a.depth), value)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitThis(expr This) (any, error) {
+	return "This", nil
+}
+
+func (a AstPrinter) visitSuper(expr Super) (any, error) {
+	return fmt.Sprintf("Super: %s", expr.method.lexeme), nil
+}
+
+func (a AstPrinter) visitInterpolation(expr Interpolation) (any, error) {
+	a.depth++
+
+	out := "Interpolation: ["
+	for index, hole := range expr.exprs {
+		value, err := hole.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sPart -> %q", strings.Repeat("\t", a.depth), expr.parts[index])
+		out += fmt.Sprintf("\n%sHole -> %s", strings.Repeat("\t", a.depth), value)
+	}
+	out += fmt.Sprintf("\n%sPart -> %q", strings.Repeat("\t", a.depth), expr.parts[len(expr.parts)-1])
+
+	a.depth--
+	return out + fmt.Sprintf("\n%s]", strings.Repeat("\t", a.depth)), nil
+}
+
+func (a AstPrinter) visitListLiteral(expr ListLiteral) (any, error) {
+	a.depth++
+
+	out := "ListLiteral: ["
+	for _, element := range expr.elements {
+		value, err := element.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sElement -> %s", strings.Repeat("\t", a.depth), value)
+	}
+
+	a.depth--
+	if len(expr.elements) > 0 {
+		out += fmt.Sprintf("\n%s", strings.Repeat("\t", a.depth))
+	}
+	return out + "]", nil
+}
+
+func (a AstPrinter) visitMapLiteral(expr MapLiteral) (any, error) {
+	a.depth++
+
+	out := "MapLiteral: {"
+	for index, keyExpr := range expr.keys {
+		key, err := keyExpr.accept(a)
+		if err != nil {
+			return "", err
+		}
+		value, err := expr.values[index].accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sKey   -> %s", strings.Repeat("\t", a.depth), key) + fmt.Sprintf(
+			"\n%sValue -> %s", strings.Repeat("\t", a.depth), value)
+	}
+
+	a.depth--
+	if len(expr.keys) > 0 {
+		out += fmt.Sprintf("\n%s", strings.Repeat("\t", a.depth))
+	}
+	return out + "}", nil
+}
+
+func (a AstPrinter) visitIndex(expr Index) (any, error) {
+	a.depth++
+
+	object, err := expr.object.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := expr.index.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := "Index:"
+	if expr.optional {
+		out = "OptionalIndex:"
+	}
+	out += fmt.Sprintf(
+		"\n%sObject -> %s", strings.Repeat("\t", a.depth), object) + fmt.Sprintf(
+		"\n%sIndex  -> %s", strings.Repeat("\t", a.depth), index)
+	a.depth--
+	return out, nil
+}
+
+func (a AstPrinter) visitMatch(expr Match) (any, error) {
+	a.depth++
+
+	subject, err := expr.subject.accept(a)
+	if err != nil {
+		return "", err
+	}
+	out := "Match:" + fmt.Sprintf("\n%sSubject -> %s", strings.Repeat("\t", a.depth), subject)
+
+	for _, arm := range expr.arms {
+		out += fmt.Sprintf("\n%sPattern -> %s", strings.Repeat("\t", a.depth), printPattern(arm.pattern))
+		if arm.guard != nil {
+			guard, err := arm.guard.accept(a)
+			if err != nil {
+				return "", err
+			}
+			out += fmt.Sprintf("\n%sGuard   -> %s", strings.Repeat("\t", a.depth), guard)
+		}
+		body, err := arm.body.accept(a)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("\n%sBody    -> %s", strings.Repeat("\t", a.depth), body)
+	}
+
+	a.depth--
+	return out, nil
+}
+
+// printPattern writes a pattern back out the way it would be written in
+// source.
+func printPattern(pattern Pattern) string {
+	switch pattern := pattern.(type) {
+	case LiteralPattern:
+		return pattern.value.lexeme
+	case WildcardPattern:
+		return "_"
+	case BindingPattern:
+		return pattern.name.lexeme
+	case ListPattern:
+		elements := make([]string, len(pattern.elements))
+		for index, element := range pattern.elements {
+			elements[index] = printPattern(element)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case MapPattern:
+		entries := make([]string, len(pattern.keys))
+		for index, key := range pattern.keys {
+			entries[index] = key.value.lexeme + ": " + printPattern(pattern.values[index])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case AlternativePattern:
+		alternatives := make([]string, len(pattern.alternatives))
+		for index, alternative := range pattern.alternatives {
+			alternatives[index] = printPattern(alternative)
+		}
+		return strings.Join(alternatives, " | ")
+	}
+	return ""
+}
+
+func (a AstPrinter) visitSetIndex(expr SetIndex) (any, error) {
+	a.depth++
+
+	object, err := expr.object.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := expr.index.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := expr.value.accept(a)
+	if err != nil {
+		return "", err
+	}
+
+	out := "SetIndex:" + fmt.Sprintf(
+		"\n%sObject -> %s", strings.Repeat("\t", a.depth), object) + fmt.Sprintf(
+		"\n%sIndex  -> %s", strings.Repeat("\t", a.depth), index) + fmt.Sprintf(
+		"\n%sValue  -> %s", strings.Repeat("\t", a.depth), value)
+	a.depth--
+	return out, nil
+}
+
 func (a AstPrinter) visitGrouping(expr Grouping) (any, error) {
 	a.depth++
 
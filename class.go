@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+
+type LoxClass struct {
+	name       string
+	superclass *LoxClass
+	methods    map[string]LoxFunction
+}
+
+func (c *LoxClass) findMethod(name string) (LoxFunction, bool) {
+	if method, ok := c.methods[name]; ok {
+		return method, true
+	}
+
+	if c.superclass != nil {
+		return c.superclass.findMethod(name)
+	}
+
+	return LoxFunction{}, false
+}
+
+// isSubclassOf reports whether c is other or inherits from it.
+func (c *LoxClass) isSubclassOf(other *LoxClass) bool {
+	for class := c; class != nil; class = class.superclass {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *LoxClass) arity() int {
+	if initializer, ok := c.findMethod("init"); ok {
+		return initializer.arity()
+	}
+	return 0
+}
+
+// call creates a new instance of the class and runs its initializer, if it
+// has one, against it.
+func (c *LoxClass) call(interpreter *Interpreter, arguments []any) (any, error) {
+	instance := &LoxInstance{class: c, fields: make(map[string]any)}
+	if initializer, ok := c.findMethod("init"); ok {
+		_, err := initializer.bind(instance).call(interpreter, arguments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return instance, nil
+}
+
+func (c *LoxClass) String() string {
+	return c.name
+}
+
+type LoxInstance struct {
+	class  *LoxClass
+	fields map[string]any
+}
+
+// get looks a property up on the instance. Fields shadow methods, and methods
+// come back bound to the instance.
+func (i *LoxInstance) get(name string) (any, bool) {
+	if value, ok := i.fields[name]; ok {
+		return value, true
+	}
+
+	if method, ok := i.class.findMethod(name); ok {
+		return method.bind(i), true
+	}
+
+	return nil, false
+}
+
+func (i *LoxInstance) set(name string, value any) {
+	i.fields[name] = value
+}
+
+func (i *LoxInstance) String() string {
+	return fmt.Sprintf("%s instance", i.class.name)
+}
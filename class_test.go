@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestClasses(t *testing.T) {
+	runCases(t, []loxCase{
+		{"print a class and an instance", `class Point {} print Point; print Point();`,
+			"Point\nPoint instance\n"},
+		{"fields", `class Box {} var b = Box(); b.value = 1; b.value = b.value + 1; print b.value;`,
+			"2\n"},
+		{"methods and this", `class Greeter { greet() { return "hi " + this.name; } }
+var g = Greeter(); g.name = "ann"; print g.greet();`,
+			"hi ann\n"},
+		{"bound methods keep this", `class A { name() { return this.n; } } var a = A(); a.n = "bound"; var m = a.name; print m();`,
+			"bound\n"},
+		{"fields shadow methods", `class A { m() { return "method"; } } var a = A(); a.m = "field"; print a.m;`,
+			"field\n"},
+		{"initializer", `class P { init(x, y) { this.x = x; this.y = y; } } var p = P(1, 2); print p.x + p.y;`,
+			"3\n"},
+		{"init returns this", `class P { init() { this.v = 1; return; } } var p = P(); print p.init() == p;`,
+			"true\n"},
+		{"initializer arity", `class P { init(x) {} } P();`,
+			"error: Expected 1 arguments but got 0.\n  --> test.lox:1:26\n  |\n1 | class P { init(x) {} } P();\n  |                          ^\n"},
+		{"class without init takes no arguments", `class P {} P(1);`,
+			"error: Expected 0 arguments but got 1.\n  --> test.lox:1:15\n  |\n1 | class P {} P(1);\n  |               ^\n"},
+		{"inheritance", `class A { hi() { return "A.hi"; } } class B < A {} print B().hi();`, "A.hi\n"},
+		{"override and super", `class A { hi() { return "A"; } }
+class B < A { hi() { return "B" + super.hi(); } }
+class C < B { hi() { return "C" + super.hi(); } }
+print C().hi();`,
+			"CBA\n"},
+		{"super binds this", `class A { name() { return this.n; } } class B < A { name() { return super.name() + "!"; } }
+var b = B(); b.n = "b"; print b.name();`,
+			"b!\n"},
+		{"inherited initializer", `class A { init(v) { this.v = v; } } class B < A {} print B(3).v;`,
+			"3\n"},
+		{"undefined property", `class A {} A().missing;`,
+			"error: Undefined property 'missing'.\n  --> test.lox:1:16\n  |\n1 | class A {} A().missing;\n  |                ^~~~~~~\n"},
+		{"property of a non-instance", `var s = "str"; s.length;`,
+			"error: Only instances and modules have properties.\n  --> test.lox:1:18\n  |\n1 | var s = \"str\"; s.length;\n  |                  ^~~~~~\n"},
+		{"field on a non-instance", `var n = 1; n.x = 2;`,
+			"error: Only instances have fields.\n  --> test.lox:1:14\n  |\n1 | var n = 1; n.x = 2;\n  |              ^\n"},
+		{"superclass is not a class", `var NotClass = "no"; class A < NotClass {}`,
+			"error: Superclass must be a class.\n  --> test.lox:1:32\n  |\n1 | var NotClass = \"no\"; class A < NotClass {}\n  |                                ^~~~~~~~\n"},
+		{"undefined super method", `class A {} class B < A { m() { return super.missing(); } } B().m();`,
+			"error: Undefined property 'missing'.\n  --> test.lox:1:45\n  |\n1 | class A {} class B < A { m() { return super.missing(); } } B().m();\n  |                                             ^~~~~~~\n  = in call to <fn m> at test.lox:1:66\n"},
+		{"inherit from itself", `class A < A {}`,
+			"error: at 'A': A class can't inherit from itself.\n  --> test.lox:1:11\n  |\n1 | class A < A {}\n  |           ^\n"},
+		{"return a value from init", `class A { init() { return 1; } }`,
+			"error: at 'return': Can't return a value from an initializer.\n  --> test.lox:1:20\n  |\n1 | class A { init() { return 1; } }\n  |                    ^~~~~~\n"},
+		{"this outside a class", `print this;`,
+			"error: at 'this': Can't use 'this' outside of a class.\n  --> test.lox:1:7\n  |\n1 | print this;\n  |       ^~~~\n"},
+		{"super outside a class", `fun f() { super.m(); }`,
+			"error: at 'super': Can't use 'super' outside of a class.\n  --> test.lox:1:11\n  |\n1 | fun f() { super.m(); }\n  |           ^~~~~\n"},
+		{"super without a superclass", `class A { m() { super.m(); } }`,
+			"error: at 'super': Can't use 'super' in a class with no superclass.\n  --> test.lox:1:17\n  |\n1 | class A { m() { super.m(); } }\n  |                 ^~~~~\n"},
+	})
+}
+
+// The resolver only lets "super" appear inside a subclass method, so these
+// scopes can't be built from Lox source. visitSuper should still fail with a
+// runtime error rather than panic if they ever are.
+func TestSuperInBadScopes(t *testing.T) {
+	keyword := NewToken(TOKEN_SUPER, "super", nil, 1, 0)
+	method := NewToken(TOKEN_IDENTIFIER, "m", nil, 1, 6)
+	class := &LoxClass{name: "A", methods: map[string]LoxFunction{}}
+
+	cases := []struct {
+		name       string
+		superclass any
+		this       any
+		want       string
+	}{
+		{"super is not a class", "no", &LoxInstance{class: class}, "'super' does not refer to a class."},
+		{"this is not an instance", class, "no", "'super' used without an instance."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			interpreter := NewInterpreter("test.lox", nil, nil, NewModuleLoader())
+			outer := NewEnvironment("super", interpreter.globals)
+			outer.define("super", c.superclass)
+			inner := NewEnvironment("this", outer)
+			inner.define("this", c.this)
+			interpreter.environment = inner
+
+			depth := 1
+			_, err := interpreter.visitSuper(Super{keyword: keyword, method: method, depth: &depth})
+			runtimeErr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("visitSuper() error = %v; want a *RuntimeError", err)
+			}
+			if runtimeErr.message != c.want {
+				t.Errorf("message = %q; want %q", runtimeErr.message, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ANSI_RESET       = "\x1b[0m"
+	ANSI_BOLD        = "\x1b[1m"
+	ANSI_RED         = "\x1b[1;31m"
+	ANSI_YELLOW      = "\x1b[1;33m"
+	ANSI_BLUE        = "\x1b[1;34m"
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+	SEVERITY_NOTE    = "note"
+)
+
+// Diagnostics renders problems found in a source file. Every report shows
+// the file and position, the offending source line and a "^~~~" underline
+// beneath the text at fault. The scanner, parser, resolver and interpreter
+// all report through it.
+type Diagnostics struct {
+	file  string
+	lines [][]rune
+}
+
+func NewDiagnostics(file string, source string) *Diagnostics {
+	var lines [][]rune
+	for _, line := range strings.Split(source, "\n") {
+		lines = append(lines, []rune(strings.TrimSuffix(line, "\r")))
+	}
+	return &Diagnostics{file: file, lines: lines}
+}
+
+// useColor reports whether text written to out should be coloured, which is
+// only when out is a terminal. NO_COLOR turns colours off everywhere.
+func useColor(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (d *Diagnostics) reportScan(line int, col int, length int, message string) {
+	d.render(os.Stdout, SEVERITY_ERROR, line, col, length, message)
+	hadError = true
+}
+
+func (d *Diagnostics) reportParse(token *Token, line int, col int, message string) {
+	where := fmt.Sprintf("at '%s'", token.lexeme)
+	if token.tokenType == TOKEN_EOF {
+		where = "at end"
+	}
+	d.render(os.Stdout, SEVERITY_ERROR, line, col, len([]rune(token.lexeme)), fmt.Sprintf("%s: %s", where, message))
+}
+
+// reportWarning points out code that is allowed but almost certainly a
+// mistake. Unlike errors, warnings don't stop the program from running.
+func (d *Diagnostics) reportWarning(token *Token, message string) {
+	d.render(os.Stdout, SEVERITY_WARNING, token.line, token.col, len([]rune(token.lexeme)), message)
+}
+
+// reportNote adds context to the error reported just before it, such as
+// where a name was declared.
+func (d *Diagnostics) reportNote(token *Token, message string) {
+	d.render(os.Stdout, SEVERITY_NOTE, token.line, token.col, len([]rune(token.lexeme)), message)
+}
+
+// SHOWN_FRAMES is how many frames of a long stack are shown at each end;
+// the ones in between, usually the same recursive call, are left out.
+const SHOWN_FRAMES = 10
+
+func (d *Diagnostics) reportRuntime(err *RuntimeError) {
+	d.render(os.Stderr, SEVERITY_ERROR, err.token.line, err.token.col, len([]rune(err.token.lexeme)), err.message)
+	for i := len(err.stack) - 1; i >= 0; i-- {
+		if i == len(err.stack)-1-SHOWN_FRAMES && i >= SHOWN_FRAMES {
+			fmt.Fprintf(os.Stderr, "  %s ... %d more frames\n", d.paint(os.Stderr, ANSI_BLUE, "="), i+1-SHOWN_FRAMES)
+			i = SHOWN_FRAMES - 1
+		}
+		frame := err.stack[i]
+		fmt.Fprintf(os.Stderr, "  %s in %s at %s:%d:%d\n", d.paint(os.Stderr, ANSI_BLUE, "="), frame.name, d.file, frame.token.line, frame.token.col+1)
+	}
+}
+
+// render writes a single diagnostic. Columns are zero based, as stored on
+// tokens, but shown one based in the location line.
+func (d *Diagnostics) render(out io.Writer, severity string, line int, col int, length int, message string) {
+	colour := ANSI_RED
+	switch severity {
+	case SEVERITY_WARNING:
+		colour = ANSI_YELLOW
+	case SEVERITY_NOTE:
+		colour = ANSI_BLUE
+	}
+
+	fmt.Fprintf(out, "%s: %s\n", d.paint(out, colour, severity), d.paint(out, ANSI_BOLD, message))
+	fmt.Fprintf(out, "  %s %s:%d:%d\n", d.paint(out, ANSI_BLUE, "-->"), d.file, line, col+1)
+	if line < 1 || line > len(d.lines) {
+		return
+	}
+
+	source := d.lines[line-1]
+	col = max(0, min(col, len(source)))
+	length = max(1, min(length, len(source)-col))
+
+	number := strconv.Itoa(line)
+	gutter := strings.Repeat(" ", len(number))
+	fmt.Fprintf(out, "%s %s\n", gutter, d.paint(out, ANSI_BLUE, "|"))
+	fmt.Fprintf(out, "%s %s %s\n", d.paint(out, ANSI_BLUE, number), d.paint(out, ANSI_BLUE, "|"), string(source))
+	fmt.Fprintf(out, "%s %s %s%s\n", gutter, d.paint(out, ANSI_BLUE, "|"), indentTo(source, col), d.paint(out, colour, "^"+strings.Repeat("~", length-1)))
+}
+
+func (d *Diagnostics) paint(out io.Writer, colour string, text string) string {
+	if !useColor(out) {
+		return text
+	}
+	return colour + text + ANSI_RESET
+}
+
+// indentTo returns the whitespace that lines the underline up with column
+// col of source. Tabs are kept so the caret stays aligned however wide the
+// terminal renders them.
+func indentTo(source []rune, col int) string {
+	var indent strings.Builder
+	for _, r := range source[:col] {
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return indent.String()
+}
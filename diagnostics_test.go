@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name    string
+		source  string
+		line    int
+		col     int
+		length  int
+		message string
+		want    string
+	}{
+		{"caret under a single character", "print 1 + ;", 1, 10, 1, "Expected expression.",
+			"error: Expected expression.\n  --> test.lox:1:11\n  |\n1 | print 1 + ;\n  |           ^\n"},
+		{"underline spans the lexeme", "print missing;", 1, 6, 7, "Undefined variable.",
+			"error: Undefined variable.\n  --> test.lox:1:7\n  |\n1 | print missing;\n  |       ^~~~~~~\n"},
+		{"later line", "var a = 1;\nvar b = c;", 2, 8, 1, "Undefined.",
+			"error: Undefined.\n  --> test.lox:2:9\n  |\n2 | var b = c;\n  |         ^\n"},
+		{"gutter widens with the line number", "\n\n\n\n\n\n\n\n\nbad", 10, 0, 3, "Bad.",
+			"error: Bad.\n  --> test.lox:10:1\n   |\n10 | bad\n   | ^~~\n"},
+		{"tabs are kept in the indent", "\tprint x;", 1, 7, 1, "Bad.",
+			"error: Bad.\n  --> test.lox:1:8\n  |\n1 | \tprint x;\n  | \t      ^\n"},
+		{"underline is clamped to the line", "print", 1, 3, 10, "Too long.",
+			"error: Too long.\n  --> test.lox:1:4\n  |\n1 | print\n  |    ^~\n"},
+		{"position past the end of the line", "print", 1, 5, 1, "At end.",
+			"error: At end.\n  --> test.lox:1:6\n  |\n1 | print\n  |      ^\n"},
+		{"line outside the source", "print", 3, 0, 1, "Nowhere.",
+			"error: Nowhere.\n  --> test.lox:3:1\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			diagnostics := NewDiagnostics("test.lox", c.source)
+			var out bytes.Buffer
+			diagnostics.render(&out, SEVERITY_ERROR, c.line, c.col, c.length, c.message)
+			if got := out.String(); got != c.want {
+				t.Errorf("got output\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsEverywhere(t *testing.T) {
+	runCases(t, []loxCase{
+		{"scan error", `print "open;`,
+			"error: Unterminated string.\n  --> test.lox:1:7\n  |\n1 | print \"open;\n  |       ^~~~~~\n"},
+		{"parse error", `print (1;`,
+			"error: at ';': Expect ')' after expression.\n  --> test.lox:1:9\n  |\n1 | print (1;\n  |         ^\n"},
+		{"multi-character operator", `print 1 <= "a";`,
+			"error: Unexpected values for operator: <=\n  --> test.lox:1:9\n  |\n1 | print 1 <= \"a\";\n  |         ^~\n"},
+		{"caret on the line after a multi-line string", "var s = \"ab\ncd\"; print s + 1;",
+			"error: Unexpected values for operator: +\n  --> test.lox:2:14\n  |\n2 | cd\"; print s + 1;\n  |              ^\n"},
+		{"caret on the line after a multi-line comment", "/* a\n b */ print nope;",
+			"error: Undefined variable : nope\n  --> test.lox:2:14\n  |\n2 |  b */ print nope;\n  |              ^~~~\n"},
+		{"underline stops at the end of the first line", "print \"ab\ncd;",
+			"error: Unterminated string.\n  --> test.lox:1:7\n  |\n1 | print \"ab\n  |       ^~~\n"},
+		{"runtime error goes with its frames", `fun f() { return -nil; }
+f();`,
+			"error: Unexpected values for operator: -\n  --> test.lox:1:18\n  |\n1 | fun f() { return -nil; }\n  |                  ^\n  = in call to <fn f> at test.lox:2:3\n"},
+	})
+}
+
+func TestUseColor(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+
+	if useColor(writer) {
+		t.Errorf("useColor(pipe) = true; want false")
+	}
+	if useColor(&bytes.Buffer{}) {
+		t.Errorf("useColor(buffer) = true; want false")
+	}
+}
+
+func TestRuntimeErrorsAreNotColouredWhenRedirected(t *testing.T) {
+	got := runLox(t, "print 1 ~/ 0;")
+	if bytes.Contains([]byte(got), []byte("\x1b[")) {
+		t.Errorf("diagnostic written to a pipe contains colour codes: %q", got)
+	}
+}
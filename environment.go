@@ -5,12 +5,24 @@ import "fmt"
 type Environment struct {
 	name   string
 	values map[string]any
-	parent *Environment
+	// Names bound with const. It stays nil until the first constant.
+	constants map[string]bool
+	parent    *Environment
 }
 
-func (e Environment) assign(name string, value any) error {
+// NewEnvironment creates an empty scope nested inside parent. Environments
+// are always shared by pointer so that closures and the interpreter see the
+// same bindings.
+func NewEnvironment(name string, parent *Environment) *Environment {
+	return &Environment{name: name, values: make(map[string]any), parent: parent}
+}
+
+func (e *Environment) assign(name string, value any) error {
 	// fmt.Println("assign ", value, " from ", e.name, " to ", e.values)
 	if _, ok := e.values[name]; ok {
+		if err := e.checkAssignable(name); err != nil {
+			return err
+		}
 		e.values[name] = value
 		return nil
 	}
@@ -22,15 +34,38 @@ func (e Environment) assign(name string, value any) error {
 	return fmt.Errorf("Undefined variable : %s", name)
 }
 
-func (e Environment) define(name string, value any) {
+// checkAssignable rejects assignments to constants and to imported names,
+// which belong to the module they were imported from.
+func (e *Environment) checkAssignable(name string) error {
+	if e.constants[name] {
+		return fmt.Errorf("Can't assign to constant '%s'.", name)
+	}
+	if _, ok := e.values[name].(*importBinding); ok {
+		return fmt.Errorf("Can't assign to imported name '%s'.", name)
+	}
+	return nil
+}
+
+// define binds name to value. A redeclaration with var at the top level
+// makes a name mutable again.
+func (e *Environment) define(name string, value any) {
 	e.values[name] = value
+	delete(e.constants, name)
 	// fmt.Println("define ", name, " from ", e.name, " to ", e.values)
 }
 
-func (e Environment) get(name string) (any, error) {
+func (e *Environment) defineConstant(name string, value any) {
+	e.define(name, value)
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+}
+
+func (e *Environment) get(name string) (any, error) {
 	// fmt.Println("get ", name, " from ", e.name, " with ", e.values)
 	if value, ok := e.values[name]; ok {
-		return value, nil
+		return readBinding(name, value)
 	}
 
 	if e.parent != nil {
@@ -39,3 +74,51 @@ func (e Environment) get(name string) (any, error) {
 
 	return nil, fmt.Errorf("Undefined variable : %s", name)
 }
+
+// ancestor returns the environment distance scopes up the parent chain.
+func (e *Environment) ancestor(distance int) *Environment {
+	env := e
+	for i := 0; i < distance; i++ {
+		env = env.parent
+	}
+	return env
+}
+
+func (e *Environment) getAt(distance int, name string) (any, error) {
+	env := e.ancestor(distance)
+	if value, ok := env.values[name]; ok {
+		return readBinding(name, value)
+	}
+
+	return nil, fmt.Errorf("Undefined variable : %s", name)
+}
+
+func (e *Environment) assignAt(distance int, name string, value any) error {
+	env := e.ancestor(distance)
+	if _, ok := env.values[name]; ok {
+		if err := env.checkAssignable(name); err != nil {
+			return err
+		}
+		env.values[name] = value
+		return nil
+	}
+
+	return fmt.Errorf("Undefined variable : %s", name)
+}
+
+// fork returns a new environment holding a copy of this environment's values
+// and constants, and sharing its parent.
+func (e *Environment) fork() *Environment {
+	values := make(map[string]any, len(e.values))
+	for name, value := range e.values {
+		values[name] = value
+	}
+	var constants map[string]bool
+	if e.constants != nil {
+		constants = make(map[string]bool, len(e.constants))
+		for name := range e.constants {
+			constants[name] = true
+		}
+	}
+	return &Environment{name: e.name, values: values, constants: constants, parent: e.parent}
+}
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestClosures(t *testing.T) {
+	runCases(t, []loxCase{
+		{"assign to an outer variable from a block", `var a = 1; { a = 2; } print a;`, "2\n"},
+		{"shadowing", `var a = "outer"; { var a = "inner"; print a; } print a;`, "inner\nouter\n"},
+		{"counter", `fun counter() { var n = 0; fun next() { n = n + 1; return n; } return next; }
+var c = counter(); c(); c(); print c();`,
+			"3\n"},
+		{"counters are independent", `fun counter() { var n = 0; fun next() { n = n + 1; return n; } return next; }
+var a = counter(); var b = counter(); a(); a(); print b();`,
+			"1\n"},
+		{"closure sees later assignments", `var x = "before"; fun f() { return x; } x = "after"; print f();`,
+			"after\n"},
+		{"loop iterations are captured separately", `var fs = nil; var gs = nil;
+for (var i = 0; i < 2; i = i + 1) { fun f() { return i; } if (i == 0) fs = f; else gs = f; }
+print fs(); print gs();`,
+			"0\n1\n"},
+		{"error inside a block", `{ var inner = 1; missing; }`,
+			"error: Undefined variable : missing\n  --> test.lox:1:18\n  |\n1 | { var inner = 1; missing; }\n  |                  ^~~~~~~\n  = in block at test.lox:1:1\n"},
+	})
+}
+
+func TestConstants(t *testing.T) {
+	runCases(t, []loxCase{
+		{"read", "const x = 1; print x;", "1\n"},
+		{"local assignment", "{\n  const x = 1;\n  x = 2;\n}",
+			"error: at 'x': Can't assign to constant 'x'.\n  --> test.lox:3:3\n  |\n3 |   x = 2;\n  |   ^\n" +
+				"note: 'x' is declared as a constant here.\n  --> test.lox:2:9\n  |\n2 |   const x = 1;\n  |         ^\n"},
+		{"compound assignment", "const x = 1; x += 1;",
+			"error: at 'x': Can't assign to constant 'x'.\n  --> test.lox:1:14\n  |\n1 | const x = 1; x += 1;\n  |              ^\n" +
+				"note: 'x' is declared as a constant here.\n  --> test.lox:1:7\n  |\n1 | const x = 1; x += 1;\n  |       ^\n"},
+		{"assigned before declared", "fun f() { x = 2; } const x = 1; f();",
+			"error: Can't assign to constant 'x'.\n  --> test.lox:1:11\n  |\n1 | fun f() { x = 2; } const x = 1; f();\n  |           ^\n" +
+				"  = in call to <fn f> at test.lox:1:35\n"},
+		{"redeclared with var", "const x = 1; var x = 2; x = 3; print x;", "3\n"},
+		{"shadowed by a local", "const x = 1; { var x = 2; x = 3; print x; }", "3\n"},
+		{"increment", "const x = 1; x++;",
+			"error: at 'x': Can't assign to constant 'x'.\n  --> test.lox:1:14\n  |\n1 | const x = 1; x++;\n  |              ^\n" +
+				"note: 'x' is declared as a constant here.\n  --> test.lox:1:7\n  |\n1 | const x = 1; x++;\n  |       ^\n"},
+		{"parameter may shadow a constant", "const x = 1; fun f(x) { x = 2; return x; } print f(0);",
+			"2\n"},
+		{"needs an initializer", "const x;",
+			"error: at ';': Expect '=' after constant name, constants must be initialized.\n  --> test.lox:1:8\n  |\n1 | const x;\n  |        ^\n"},
+		{"assigned in a closure", "{ const x = 1; fun f() { x = 2; } }",
+			"error: at 'x': Can't assign to constant 'x'.\n  --> test.lox:1:26\n  |\n1 | { const x = 1; fun f() { x = 2; } }\n  |                          ^\n" +
+				"note: 'x' is declared as a constant here.\n  --> test.lox:1:9\n  |\n1 | { const x = 1; fun f() { x = 2; } }\n  |         ^\n"},
+		{"every assignment is reported", "const a = 1; a = 2; a = 3;",
+			"error: at 'a': Can't assign to constant 'a'.\n  --> test.lox:1:14\n  |\n1 | const a = 1; a = 2; a = 3;\n  |              ^\n" +
+				"note: 'a' is declared as a constant here.\n  --> test.lox:1:7\n  |\n1 | const a = 1; a = 2; a = 3;\n  |       ^\n" +
+				"error: at 'a': Can't assign to constant 'a'.\n  --> test.lox:1:21\n  |\n1 | const a = 1; a = 2; a = 3;\n  |                     ^\n" +
+				"note: 'a' is declared as a constant here.\n  --> test.lox:1:7\n  |\n1 | const a = 1; a = 2; a = 3;\n  |       ^\n"},
+	})
+}
+
+// An error part way through a block must not leave later REPL lines running
+// in the block's scope.
+func TestReplScopeAfterError(t *testing.T) {
+	showTokens, showAst = false, false
+	env := make(map[string]any)
+	constants := make(map[string]bool)
+	loader := NewModuleLoader()
+	got := capture(t, func() {
+		run(REPL_FILE, "{ var inner = 1; missing; }", &env, constants, loader)
+		run(REPL_FILE, "var outer = 2;", &env, constants, loader)
+		run(REPL_FILE, "print outer;", &env, constants, loader)
+		run(REPL_FILE, "print inner;", &env, constants, loader)
+	})
+
+	want := "error: Undefined variable : missing\n  --> <stdin>:1:18\n  |\n1 | { var inner = 1; missing; }\n  |                  ^~~~~~~\n" +
+		"  = in block at <stdin>:1:1\n2\n" +
+		"error: Undefined variable : inner\n  --> <stdin>:1:7\n  |\n1 | print inner;\n  |       ^~~~~\n"
+	if got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
+
+// The REPL runs each line with a fresh interpreter, so constness has to
+// travel from line to line with the values.
+func TestConstantsAcrossReplLines(t *testing.T) {
+	showTokens, showAst = false, false
+	env := make(map[string]any)
+	constants := make(map[string]bool)
+	loader := NewModuleLoader()
+	got := capture(t, func() {
+		run(REPL_FILE, "const x = 1;", &env, constants, loader)
+		run(REPL_FILE, "x = 2;", &env, constants, loader)
+		run(REPL_FILE, "print x;", &env, constants, loader)
+	})
+
+	want := "error: Can't assign to constant 'x'.\n  --> <stdin>:1:1\n  |\n1 | x = 2;\n  | ^\n1\n"
+	if got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestForkCopiesConstants(t *testing.T) {
+	env := NewEnvironment("test", nil)
+	env.defineConstant("x", 1)
+	fork := env.fork()
+	fork.define("x", 2)
+
+	if err := env.assign("x", 3); err == nil {
+		t.Errorf("assigning to x in the original environment succeeded; want an error")
+	}
+	if err := fork.assign("x", 3); err != nil {
+		t.Errorf("assigning to x in the fork failed: %v", err)
+	}
+}
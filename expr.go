@@ -3,8 +3,22 @@ package main
 type ExprVisitor interface {
 	visitAssign(expr Assign) (any, error)
 	visitVariable(expr Variable) (any, error)
+	visitCompoundAssign(expr CompoundAssign) (any, error)
+	visitIncrement(expr Increment) (any, error)
 	visitTernary(expr Ternary) (any, error)
 	visitBinary(expr Binary) (any, error)
+	visitLogical(expr Logical) (any, error)
+	visitCall(expr Call) (any, error)
+	visitGet(expr Get) (any, error)
+	visitSet(expr Set) (any, error)
+	visitThis(expr This) (any, error)
+	visitSuper(expr Super) (any, error)
+	visitInterpolation(expr Interpolation) (any, error)
+	visitListLiteral(expr ListLiteral) (any, error)
+	visitMapLiteral(expr MapLiteral) (any, error)
+	visitIndex(expr Index) (any, error)
+	visitMatch(expr Match) (any, error)
+	visitSetIndex(expr SetIndex) (any, error)
 	visitGrouping(expr Grouping) (any, error)
 	visitLiteral(expr Literal) (any, error)
 	visitOperator(expr Operator) (any, error)
@@ -15,9 +29,23 @@ type Expr interface {
 	accept(visitor ExprVisitor) (any, error)
 }
 
+// GLOBAL_DEPTH is the depth of a variable the Resolver did not find in any
+// local scope. Such variables are looked up by name in the globals of the
+// module the code was written in.
+const GLOBAL_DEPTH = -1
+
+// newDepth allocates the slot the Resolver fills in with the number of scopes
+// between a variable reference and its declaration. Expressions are passed
+// around by value so the slot is shared through a pointer.
+func newDepth() *int {
+	depth := GLOBAL_DEPTH
+	return &depth
+}
+
 type Assign struct {
 	name  Token
 	value Expr
+	depth *int
 }
 
 func (a Assign) accept(visitor ExprVisitor) (any, error) {
@@ -25,13 +53,38 @@ func (a Assign) accept(visitor ExprVisitor) (any, error) {
 }
 
 type Variable struct {
-	name Token
+	name  Token
+	depth *int
 }
 
 func (v Variable) accept(visitor ExprVisitor) (any, error) {
 	return visitor.visitVariable(v)
 }
 
+// CompoundAssign is an assignment such as x += 1, whose target is a
+// Variable, Get or Index expression.
+type CompoundAssign struct {
+	target   Expr
+	operator Token
+	value    Expr
+}
+
+func (c CompoundAssign) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitCompoundAssign(c)
+}
+
+// Increment is ++ or -- on a Variable, Get or Index target, written before
+// or after it.
+type Increment struct {
+	target   Expr
+	operator Token
+	prefix   bool
+}
+
+func (i Increment) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitIncrement(i)
+}
+
 type Ternary struct {
 	condition Expr
 	left      Expr
@@ -52,6 +105,138 @@ func (b Binary) accept(visitor ExprVisitor) (any, error) {
 	return visitor.visitBinary(b)
 }
 
+type Logical struct {
+	left     Expr
+	operator Token
+	right    Expr
+}
+
+func (l Logical) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitLogical(l)
+}
+
+type Call struct {
+	callee    Expr
+	paren     Token
+	arguments []Expr
+}
+
+func (c Call) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitCall(c)
+}
+
+// Get reads a property. An optional Get, written "?.", gives nil when the
+// object is nil, and so does the rest of the chain of accesses after it.
+type Get struct {
+	object   Expr
+	name     Token
+	optional bool
+}
+
+func (g Get) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitGet(g)
+}
+
+type Set struct {
+	object Expr
+	name   Token
+	value  Expr
+}
+
+func (s Set) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitSet(s)
+}
+
+type This struct {
+	keyword Token
+	depth   *int
+}
+
+func (t This) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitThis(t)
+}
+
+type Super struct {
+	keyword Token
+	method  Token
+	depth   *int
+}
+
+func (s Super) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitSuper(s)
+}
+
+type Interpolation struct {
+	token Token
+	parts []string
+	exprs []Expr
+}
+
+func (i Interpolation) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitInterpolation(i)
+}
+
+type ListLiteral struct {
+	bracket  Token
+	elements []Expr
+}
+
+func (l ListLiteral) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitListLiteral(l)
+}
+
+type MapLiteral struct {
+	brace  Token
+	keys   []Expr
+	values []Expr
+}
+
+func (m MapLiteral) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitMapLiteral(m)
+}
+
+// Index reads a list element or map entry. An optional Index, written
+// "?.[", gives nil when the object is nil, as does the rest of the chain.
+type Index struct {
+	object   Expr
+	bracket  Token
+	index    Expr
+	optional bool
+}
+
+func (i Index) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitIndex(i)
+}
+
+// Match gives the body of the first arm whose pattern matches the subject
+// and whose guard, if it has one, is truthy.
+type Match struct {
+	keyword Token
+	subject Expr
+	arms    []MatchArm
+}
+
+type MatchArm struct {
+	pattern Pattern
+	guard   Expr
+	body    Expr
+}
+
+func (m Match) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitMatch(m)
+}
+
+type SetIndex struct {
+	object  Expr
+	bracket Token
+	index   Expr
+	value   Expr
+}
+
+func (s SetIndex) accept(visitor ExprVisitor) (any, error) {
+	return visitor.visitSetIndex(s)
+}
+
 type Grouping struct {
 	expression Expr
 }
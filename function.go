@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+type LoxCallable interface {
+	arity() int
+	call(interpreter *Interpreter, arguments []any) (any, error)
+}
+
+type LoxFunction struct {
+	declaration FunctionStatement
+	closure     *Environment
+	// The globals of the module the function was declared in, which its
+	// global variables are looked up in wherever it is called from.
+	globals       *Environment
+	isInitializer bool
+}
+
+// bind returns a copy of the method whose closure has "this" bound to
+// instance.
+func (f LoxFunction) bind(instance *LoxInstance) LoxFunction {
+	env := NewEnvironment(fmt.Sprintf("THISENV_%s", f.declaration.name.lexeme), f.closure)
+	env.define("this", instance)
+	return LoxFunction{declaration: f.declaration, closure: env, globals: f.globals, isInitializer: f.isInitializer}
+}
+
+func (f LoxFunction) arity() int {
+	return len(f.declaration.params)
+}
+
+func (f LoxFunction) call(interpreter *Interpreter, arguments []any) (any, error) {
+	env := NewEnvironment(fmt.Sprintf("FUNENV_%s", f.declaration.name.lexeme), f.closure)
+	for i, param := range f.declaration.params {
+		env.define(param.lexeme, arguments[i])
+	}
+
+	// Built-in methods, such as those of Error, don't use any globals.
+	if f.globals != nil {
+		callerGlobals := interpreter.globals
+		interpreter.globals = f.globals
+		defer func() { interpreter.globals = callerGlobals }()
+	}
+
+	err := interpreter.executeBlock(f.declaration.body, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// A return statement leaves its value behind as the pending signal.
+	var value any
+	if interpreter.signal != nil && interpreter.signal.kind == SIGNAL_RETURN {
+		value = interpreter.signal.value
+		interpreter.signal = nil
+	}
+
+	// Initializers always hand back the instance being initialized.
+	if f.isInitializer {
+		return f.closure.getAt(0, "this")
+	}
+
+	return value, nil
+}
+
+func (f LoxFunction) String() string {
+	return fmt.Sprintf("<fn %s>", f.declaration.name.lexeme)
+}
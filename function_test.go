@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	runCases(t, []loxCase{
+		{"call", `fun greet(name) { print "hi " + name; } greet("bob");`, "hi bob\n"},
+		{"return value", `fun add(a, b) { return a + b; } print add(1, 2);`, "3\n"},
+		{"return without value", `fun f() { return; } print f();`, "nil\n"},
+		{"no return gives nil", `fun f() {} print f();`, "nil\n"},
+		{"return unwinds loops", `fun f() { while (true) { for (;;) { return "out"; } } } print f();`,
+			"out\n"},
+		{"recursion", `fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); } print fib(10);`,
+			"55\n"},
+		{"functions are values", `fun f() { return "f"; } var g = f; print g();`, "f\n"},
+		{"print a function", `fun f() {} print f;`, "<fn f>\n"},
+		{"comma operator inside an argument", `fun f(a) { return a; } print f((1, 2));`, "2\n"},
+		{"curried call", `fun outer() { fun inner() { return "inner"; } return inner; } print outer()();`,
+			"inner\n"},
+		{"deep recursion", "fun depth(n) { if (n == 0) return 0; return 1 + depth(n - 1); } print depth(5000);",
+			"5000\n"},
+		{"blocks don't count towards the depth", "fun depth(n) { if (n == 0) { return 0; } { return depth(n - 1) + 1; } } print depth(9000);",
+			"9000\n"},
+		{"stack overflow is caught", "fun f() { return f(); } try { f(); } catch (e) { print e.message; }",
+			"Stack overflow.\n"},
+		{"too few arguments", `fun f(a, b) {} f(1);`,
+			"error: Expected 2 arguments but got 1.\n  --> test.lox:1:19\n  |\n1 | fun f(a, b) {} f(1);\n  |                   ^\n"},
+		{"too many arguments", `fun f() {} f(1);`,
+			"error: Expected 0 arguments but got 1.\n  --> test.lox:1:15\n  |\n1 | fun f() {} f(1);\n  |               ^\n"},
+		{"call a non-function", `"text"();`,
+			"error: Can only call functions and classes.\n  --> test.lox:1:8\n  |\n1 | \"text\"();\n  |        ^\n"},
+		{"return at top level", `return 1;`,
+			"error: at 'return': Can't return from top-level code.\n  --> test.lox:1:1\n  |\n1 | return 1;\n  | ^~~~~~\n"},
+		{"missing parameter name", `fun f(1);`,
+			"error: at '1': Expected parameter name.\n  --> test.lox:1:7\n  |\n1 | fun f(1);\n  |       ^\n"},
+		{"bad parameter list with a body", `fun f(1) { print 1; }`,
+			"error: at '1': Expected parameter name.\n  --> test.lox:1:7\n  |\n1 | fun f(1) { print 1; }\n  |       ^\n"},
+		{"missing closing paren", `fun f() {} f(1;`,
+			"error: at ';': Expected ')' after arguments.\n  --> test.lox:1:15\n  |\n1 | fun f() {} f(1;\n  |               ^\n"},
+	})
+}
+
+// Only the frames at either end of a runaway recursion are shown.
+func TestStackOverflow(t *testing.T) {
+	got := runLox(t, "fun f(n) {\n  return f(n + 1);\n}\nf(0);")
+
+	frame := "  = in call to <fn f> at test.lox:2:17\n"
+	want := "error: Stack overflow.\n  --> test.lox:2:17\n  |\n2 |   return f(n + 1);\n  |                 ^\n" +
+		strings.Repeat(frame, SHOWN_FRAMES) +
+		"  = ... 9980 more frames\n" +
+		strings.Repeat(frame, SHOWN_FRAMES-1) +
+		"  = in call to <fn f> at test.lox:4:4\n"
+	if got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
+
+// Blocks are shown on the stack but only calls count towards its limit, so
+// the overflow comes after MAX_FRAMES calls with a block each.
+func TestStackOverflowThroughBlocks(t *testing.T) {
+	got := runLox(t, "fun f(n) {\n  {\n    return f(n + 1);\n  }\n}\nf(0);")
+
+	frames := "  = in block at test.lox:2:3\n  = in call to <fn f> at test.lox:3:19\n"
+	want := "error: Stack overflow.\n  --> test.lox:3:19\n  |\n3 |     return f(n + 1);\n  |                   ^\n" +
+		strings.Repeat(frames, SHOWN_FRAMES/2) +
+		fmt.Sprintf("  = ... %d more frames\n", 2*MAX_FRAMES-2*SHOWN_FRAMES) +
+		strings.Repeat(frames, SHOWN_FRAMES/2-1) +
+		"  = in block at test.lox:2:3\n  = in call to <fn f> at test.lox:6:4\n"
+	if got != want {
+		t.Errorf("got output\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"path/filepath"
+	"strings"
 )
 
 func isTruthy(value any) bool {
@@ -18,12 +23,76 @@ func isTruthy(value any) bool {
 	return true
 }
 
+// stringify turns a runtime value into the text print shows for it.
+func stringify(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return formatFloat(value)
+	}
+	return fmt.Sprint(value)
+}
+
+// inspect is like stringify but quotes strings so they can be told apart
+// inside collections and at the REPL.
+func inspect(value any) string {
+	return inspectNested(value, make(map[any]bool))
+}
+
+// inspectNested inspects a value found inside the collections in seen, which
+// are still being printed. A collection that contains itself is shown as
+// [...] or {...} where it comes up again instead of being printed forever.
+func inspectNested(value any, seen map[any]bool) string {
+	switch value := value.(type) {
+	case string:
+		return fmt.Sprintf("\"%s\"", value)
+	case *LoxList:
+		return value.inspect(seen)
+	case *LoxMap:
+		return value.inspect(seen)
+	}
+	return stringify(value)
+}
+
+const (
+	SIGNAL_BREAK = iota
+	SIGNAL_CONTINUE
+	SIGNAL_RETURN
+)
+
+// controlSignal interrupts the normal flow of statements without being an
+// error. It is raised by a jump statement and travels outwards until the
+// statement it targets picks it up.
+type controlSignal struct {
+	kind  int
+	label string
+	value any
+}
+
 type Interpreter struct {
-	environment Environment
+	// The globals of the module whose code is running. A call to a function
+	// declared in another module switches to that module's globals.
+	globals     *Environment
+	environment *Environment
 	scopeDepth  int
+	signal      *controlSignal
+	// Blocks and calls currently being executed, innermost last.
+	frames []Frame
+	// Number of the frames that are calls, which is what MAX_FRAMES limits.
+	calls int
+	// The file being run, which imports are resolved against, and the
+	// loader shared with every module it imports.
+	file   string
+	loader *ModuleLoader
+	// Names declared with export at the top level.
+	exports map[string]bool
 }
 
-func NewInterpreter(existingEnv *map[string]any) *Interpreter {
+// NewInterpreter runs code with the globals in existingEnv, or fresh ones
+// when it is nil. constants, when given, records which of those globals are
+// constants and is updated as more are declared.
+func NewInterpreter(file string, existingEnv *map[string]any, constants map[string]bool, loader *ModuleLoader) *Interpreter {
 
 	var env map[string]any
 	if existingEnv == nil {
@@ -32,8 +101,14 @@ func NewInterpreter(existingEnv *map[string]any) *Interpreter {
 		env = *existingEnv
 	}
 
+	globals := &Environment{name: "INTENV_BASE", values: env, constants: constants}
+	defineNatives(globals)
 	return &Interpreter{
-		environment: Environment{name: "INTENV_BASE", values: env},
+		globals:     globals,
+		environment: globals,
+		file:        file,
+		loader:      loader,
+		exports:     make(map[string]bool),
 	}
 }
 
@@ -68,41 +143,331 @@ func (i *Interpreter) visitVarDeclarationStatement(stmt VarDeclarationStatement)
 		}
 	}
 
-	i.environment.define(stmt.name.lexeme, value)
+	if stmt.constant {
+		i.environment.defineConstant(stmt.name.lexeme, value)
+	} else {
+		i.environment.define(stmt.name.lexeme, value)
+	}
 	return nil, nil
 }
 
 func (i *Interpreter) visitBlockStatement(stmt BlockStatement) (any, error) {
 	i.scopeDepth++
-	i.executeBlock(stmt.stmts, Environment{name: fmt.Sprintf("INTENV_%d", i.scopeDepth), values: make(map[string]any)})
-	i.scopeDepth--
-	return nil, nil
+	i.pushFrame("block", stmt.brace)
+	defer func() {
+		i.popFrame()
+		i.scopeDepth--
+	}()
+
+	env := NewEnvironment(fmt.Sprintf("INTENV_%d", i.scopeDepth), i.environment)
+	return nil, i.executeBlock(stmt.stmts, env)
 }
 
-func (i *Interpreter) executeBlock(stmts []Statement, env Environment) error {
+// executeBlock runs stmts inside env. The previous environment is restored
+// however the block is left, be it normally, through a control signal or
+// with an error.
+func (i *Interpreter) executeBlock(stmts []Statement, env *Environment) error {
 	previousEnv := i.environment
-
-	env.parent = &previousEnv
 	i.environment = env
+	defer func() { i.environment = previousEnv }()
 
 	for _, stmt := range stmts {
 		_, err := i.execute(stmt)
 		if err != nil {
 			return err
 		}
+		if i.signal != nil {
+			break
+		}
 	}
 
-	i.environment = previousEnv
-
 	return nil
 }
 
+func (i *Interpreter) visitIfStatement(stmt IfStatement) (any, error) {
+	condition, err := i.evaluate(stmt.condition)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTruthy(condition) {
+		return i.execute(stmt.thenBranch)
+	} else if stmt.elseBranch != nil {
+		return i.execute(stmt.elseBranch)
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) visitWhileStatement(stmt WhileStatement) (any, error) {
+	for {
+		condition, err := i.evaluate(stmt.condition)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(condition) {
+			break
+		}
+
+		_, err = i.execute(stmt.body)
+		if err != nil {
+			return nil, err
+		}
+		if i.loopShouldExit(stmt.label) {
+			break
+		}
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) visitForStatement(stmt ForStatement) (any, error) {
+	previousEnv := i.environment
+	i.scopeDepth++
+	defer func() {
+		i.environment = previousEnv
+		i.scopeDepth--
+	}()
+
+	i.environment = NewEnvironment(fmt.Sprintf("INTENV_%d", i.scopeDepth), previousEnv)
+
+	if stmt.initializer != nil {
+		_, err := i.execute(stmt.initializer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if stmt.condition != nil {
+			condition, err := i.evaluate(stmt.condition)
+			if err != nil {
+				return nil, err
+			}
+			if !isTruthy(condition) {
+				break
+			}
+		}
+
+		_, err := i.execute(stmt.body)
+		if err != nil {
+			return nil, err
+		}
+		if i.loopShouldExit(stmt.label) {
+			break
+		}
+
+		// Every iteration runs in a fresh copy of the loop variables so that
+		// anything captured by the body keeps the value it saw.
+		i.environment = i.environment.fork()
+
+		if stmt.increment != nil {
+			_, err = i.evaluate(stmt.increment)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) visitBreakStatement(stmt BreakStatement) (any, error) {
+	i.signal = &controlSignal{kind: SIGNAL_BREAK, label: labelName(stmt.label)}
+	return nil, nil
+}
+
+func (i *Interpreter) visitContinueStatement(stmt ContinueStatement) (any, error) {
+	i.signal = &controlSignal{kind: SIGNAL_CONTINUE, label: labelName(stmt.label)}
+	return nil, nil
+}
+
+// loopShouldExit consumes a pending break or continue aimed at the loop with
+// the given label and reports whether the loop has to stop. A signal aimed at
+// an enclosing loop is left pending and also stops this loop.
+func (i *Interpreter) loopShouldExit(label *Token) bool {
+	if i.signal == nil {
+		return false
+	}
+
+	if i.signal.kind == SIGNAL_RETURN {
+		return true
+	}
+
+	if i.signal.label != "" && i.signal.label != labelName(label) {
+		return true
+	}
+
+	exit := i.signal.kind == SIGNAL_BREAK
+	i.signal = nil
+	return exit
+}
+
+func labelName(label *Token) string {
+	if label == nil {
+		return ""
+	}
+	return label.lexeme
+}
+
+func (i *Interpreter) visitFunctionStatement(stmt FunctionStatement) (any, error) {
+	i.environment.define(stmt.name.lexeme, LoxFunction{declaration: stmt, closure: i.environment, globals: i.globals})
+	return nil, nil
+}
+
+func (i *Interpreter) visitClassStatement(stmt ClassStatement) (any, error) {
+	var superclass *LoxClass
+	if stmt.superclass != nil {
+		value, err := i.evaluate(*stmt.superclass)
+		if err != nil {
+			return nil, err
+		}
+
+		class, ok := value.(*LoxClass)
+		if !ok {
+			return nil, i.runtimeError(stmt.superclass.name, "Superclass must be a class.")
+		}
+		superclass = class
+	}
+
+	i.environment.define(stmt.name.lexeme, nil)
+
+	// Methods of a subclass close over an extra scope holding "super".
+	enclosing := i.environment
+	if superclass != nil {
+		i.environment = NewEnvironment(fmt.Sprintf("SUPERENV_%s", stmt.name.lexeme), enclosing)
+		i.environment.define("super", superclass)
+	}
+
+	methods := make(map[string]LoxFunction)
+	for _, method := range stmt.methods {
+		methods[method.name.lexeme] = LoxFunction{
+			declaration:   method,
+			closure:       i.environment,
+			globals:       i.globals,
+			isInitializer: method.name.lexeme == "init",
+		}
+	}
+
+	i.environment = enclosing
+	class := &LoxClass{name: stmt.name.lexeme, superclass: superclass, methods: methods}
+	return nil, i.environment.assign(stmt.name.lexeme, class)
+}
+
+func (i *Interpreter) visitReturnStatement(stmt ReturnStatement) (any, error) {
+	var value any
+	var err error
+	if stmt.value != nil {
+		value, err = i.evaluate(stmt.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i.signal = &controlSignal{kind: SIGNAL_RETURN, value: value}
+	return nil, nil
+}
+
+func (i *Interpreter) visitImportStatement(stmt ImportStatement) (any, error) {
+	module, err := i.loader.load(i.modulePath(stmt.path.literal.(string)))
+	if err != nil {
+		return nil, i.runtimeError(stmt.path, "%s", err.Error())
+	}
+
+	if stmt.alias != nil {
+		i.environment.define(stmt.alias.lexeme, module)
+	}
+	for _, name := range stmt.names {
+		if _, ok := module.get(name.lexeme); !ok {
+			return nil, i.runtimeError(name, "Module '%s' has no export named '%s'.", module.name, name.lexeme)
+		}
+		i.environment.define(name.lexeme, &importBinding{module: module, name: name.lexeme})
+	}
+
+	return nil, nil
+}
+
+// modulePath resolves an imported path against the directory of the file
+// doing the import, or the working directory at the REPL.
+func (i *Interpreter) modulePath(path string) string {
+	if !filepath.IsAbs(path) && i.file != REPL_FILE {
+		path = filepath.Join(filepath.Dir(i.file), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func (i *Interpreter) visitExportStatement(stmt ExportStatement) (any, error) {
+	_, err := i.execute(stmt.declaration)
+	if err != nil {
+		return nil, err
+	}
+
+	i.exports[stmt.name.lexeme] = true
+	return nil, nil
+}
+
+func (i *Interpreter) visitThrowStatement(stmt ThrowStatement) (any, error) {
+	value, err := i.evaluate(stmt.value)
+	if err != nil {
+		return nil, err
+	}
+
+	// An uncaught Error, or an instance of a subclass of it, is reported
+	// with its own message.
+	message := fmt.Sprintf("Uncaught exception: %s", inspect(value))
+	if instance, ok := value.(*LoxInstance); ok && instance.class.isSubclassOf(errorClass) {
+		if text, ok := instance.fields["message"]; ok {
+			message = stringify(text)
+		}
+	}
+
+	thrown := i.runtimeError(stmt.keyword, "%s", message)
+	thrown.thrown = true
+	thrown.value = value
+	return nil, thrown
+}
+
+// visitTryStatement runs the try block, hands a RuntimeError escaping it to
+// the catch clause and then runs the finally clause however the statement is
+// being left: normally, through an error or through a break, continue or
+// return signal.
+func (i *Interpreter) visitTryStatement(stmt TryStatement) (any, error) {
+	_, err := i.execute(stmt.body)
+
+	var runtimeErr *RuntimeError
+	if err != nil && stmt.catchBody != nil && errors.As(err, &runtimeErr) {
+		env := NewEnvironment("CATCHENV", i.environment)
+		env.define(stmt.catchName.lexeme, caughtValue(runtimeErr))
+		err = i.executeBlock([]Statement{*stmt.catchBody}, env)
+	}
+
+	if stmt.finallyBody != nil {
+		// The pending signal is put aside while the finally block runs and
+		// resumed afterwards, unless the finally block jumps or fails itself.
+		signal := i.signal
+		i.signal = nil
+		_, finallyErr := i.execute(*stmt.finallyBody)
+		if finallyErr != nil {
+			return nil, finallyErr
+		}
+		if i.signal != nil {
+			return nil, nil
+		}
+		i.signal = signal
+	}
+
+	return nil, err
+}
+
 func (i *Interpreter) visitPrintStatement(stmt PrintStatement) (any, error) {
 	value, err := i.evaluate(stmt.expr)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("%v\n", value)
+	fmt.Println(stringify(value))
 	return nil, nil
 }
 
@@ -111,17 +476,130 @@ func (i *Interpreter) visitAssign(expr Assign) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = i.environment.assign(expr.name.lexeme, value)
+	err = i.assignVariable(expr.name, *expr.depth, value)
 	if err != nil {
 		return nil, err
 	}
 	return value, nil
 }
 
+func (i *Interpreter) assignVariable(name Token, depth int, value any) error {
+	var err error
+	if depth == GLOBAL_DEPTH {
+		err = i.globals.assign(name.lexeme, value)
+	} else {
+		err = i.environment.assignAt(depth, name.lexeme, value)
+	}
+	if err != nil {
+		return i.runtimeError(name, "%s", err.Error())
+	}
+	return nil
+}
+
+// compoundOperators maps compound assignment and increment tokens to the
+// binary operator they apply.
+var compoundOperators = map[int]int{
+	TOKEN_PLUS_EQUAL:  TOKEN_PLUS,
+	TOKEN_MINUS_EQUAL: TOKEN_MINUS,
+	TOKEN_STAR_EQUAL:  TOKEN_STAR,
+	TOKEN_SLASH_EQUAL: TOKEN_SLASH,
+	TOKEN_PLUS_PLUS:   TOKEN_PLUS,
+	TOKEN_MINUS_MINUS: TOKEN_MINUS,
+}
+
+func (i *Interpreter) visitCompoundAssign(expr CompoundAssign) (any, error) {
+	_, value, err := i.update(expr.target, func(old any) (any, error) {
+		right, err := i.evaluate(expr.value)
+		if err != nil {
+			return nil, err
+		}
+		return i.binaryOperation(expr.operator, compoundOperators[expr.operator.tokenType], old, right)
+	})
+	return value, err
+}
+
+func (i *Interpreter) visitIncrement(expr Increment) (any, error) {
+	old, value, err := i.update(expr.target, func(old any) (any, error) {
+		return i.binaryOperation(expr.operator, compoundOperators[expr.operator.tokenType], old, int64(1))
+	})
+	if expr.prefix {
+		return value, err
+	}
+	return old, err
+}
+
+// update reads the current value of an assignable target, computes a new one
+// from it and stores it back, returning both. The parts of the target, such
+// as the list and index in xs[i], are evaluated only once.
+func (i *Interpreter) update(target Expr, compute func(old any) (any, error)) (any, any, error) {
+	switch target := target.(type) {
+	case Variable:
+		old, err := i.lookUpVariable(target.name, *target.depth)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := compute(old)
+		if err != nil {
+			return nil, nil, err
+		}
+		return old, value, i.assignVariable(target.name, *target.depth, value)
+	case Get:
+		object, err := i.evaluate(target.object)
+		if err != nil {
+			return nil, nil, err
+		}
+		instance, ok := object.(*LoxInstance)
+		if !ok {
+			return nil, nil, i.runtimeError(target.name, "Only instances have fields.")
+		}
+		old, ok := instance.get(target.name.lexeme)
+		if !ok {
+			return nil, nil, i.runtimeError(target.name, "Undefined property '%s'.", target.name.lexeme)
+		}
+		value, err := compute(old)
+		if err != nil {
+			return nil, nil, err
+		}
+		instance.set(target.name.lexeme, value)
+		return old, value, nil
+	case Index:
+		object, err := i.evaluate(target.object)
+		if err != nil {
+			return nil, nil, err
+		}
+		index, err := i.evaluate(target.index)
+		if err != nil {
+			return nil, nil, err
+		}
+		old, err := i.getIndex(target.bracket, object, index)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := compute(old)
+		if err != nil {
+			return nil, nil, err
+		}
+		return old, value, i.setIndex(target.bracket, object, index, value)
+	}
+
+	// The parser only builds updates of the targets above.
+	panic(fmt.Sprintf("unexpected update target %T", target))
+}
+
 func (i *Interpreter) visitVariable(expr Variable) (any, error) {
-	value, err := i.environment.get(expr.name.lexeme)
+	return i.lookUpVariable(expr.name, *expr.depth)
+}
+
+func (i *Interpreter) lookUpVariable(name Token, depth int) (any, error) {
+	var value any
+	var err error
+	if depth == GLOBAL_DEPTH {
+		value, err = i.globals.get(name.lexeme)
+	} else {
+		value, err = i.environment.getAt(depth, name.lexeme)
+	}
 	if err != nil {
-		return nil, err
+		return nil, i.runtimeError(name, "%s", err.Error())
 	}
 
 	return value, nil
@@ -138,31 +616,29 @@ func (i *Interpreter) visitGrouping(expr Grouping) (any, error) {
 func (i *Interpreter) visitUnary(expr Unary) (any, error) {
 	right, err := i.evaluate(expr.right)
 	if err != nil {
-		return err, nil
+		return nil, err
 	}
 
 	switch expr.operator.tokenType {
 	case TOKEN_BANG:
 		return !isTruthy(right), nil
 	case TOKEN_MINUS:
-		if right, ok := right.(float64); ok {
-			return -right, nil
+		if negated, ok := negateNumber(right); ok {
+			return negated, nil
 		}
+	case TOKEN_TILDE:
+		switch right := right.(type) {
+		case int64:
+			return ^right, nil
+		case *big.Int:
+			return normalizeInt(new(big.Int).Not(right)), nil
+		}
+		return nil, i.runtimeError(expr.operator, "Operand of '~' must be an integer.")
 	default:
-		return fmt.Errorf(
-			"[line: %d, col: %d] Unknown unary operator: %s",
-			expr.operator.line,
-			expr.operator.col,
-			expr.operator.lexeme,
-		), nil
+		return nil, i.runtimeError(expr.operator, "Unknown unary operator: %s", expr.operator.lexeme)
 	}
 
-	return fmt.Errorf(
-		"[line: %d, col: %d] Unexpected values for operator: %s",
-		expr.operator.line,
-		expr.operator.col,
-		expr.operator.lexeme,
-	), nil
+	return nil, i.runtimeError(expr.operator, "Unexpected values for operator: %s", expr.operator.lexeme)
 }
 
 func (i *Interpreter) visitBinary(expr Binary) (any, error) {
@@ -175,91 +651,610 @@ func (i *Interpreter) visitBinary(expr Binary) (any, error) {
 		return nil, err
 	}
 
-	switch expr.operator.operator.tokenType {
+	return i.binaryOperation(expr.operator.operator, expr.operator.operator.tokenType, left, right)
+}
+
+// binaryOperation applies the binary operator tokenType to two evaluated
+// operands. Errors are reported at operator, which for compound assignments
+// and increments is the "+=" or "++" token rather than a plain "+".
+func (i *Interpreter) binaryOperation(operator Token, tokenType int, left any, right any) (any, error) {
+	switch tokenType {
 	case TOKEN_COMMA:
 		return right, nil
 	case TOKEN_BANG_EQUAL:
-		return left != right, nil
+		return !isEqual(left, right), nil
 	case TOKEN_EQUAL_EQUAL:
-		return left == right, nil
-	case TOKEN_GREATER:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-		if okLeft && okRight {
-			return left > right, nil
+		return isEqual(left, right), nil
+	case TOKEN_PLUS:
+		leftStr, okLeftStr := left.(string)
+		rightStr, okRightStr := right.(string)
+		if okLeftStr && okRightStr {
+			return leftStr + rightStr, nil
 		}
-	case TOKEN_GREATER_EQUAL:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-		if okLeft && okRight {
-			return left >= right, nil
+	}
+
+	leftInt, okLeft := left.(int64)
+	rightInt, okRight := right.(int64)
+	if okLeft && okRight {
+		return i.integerOperation(operator, tokenType, leftInt, rightInt)
+	}
+
+	leftBig, okLeft := toBigInt(left)
+	rightBig, okRight := toBigInt(right)
+	if okLeft && okRight {
+		return i.bigOperation(operator, tokenType, leftBig, rightBig)
+	}
+
+	leftNum, okLeft := toFloat(left)
+	rightNum, okRight := toFloat(right)
+	if okLeft && okRight {
+		// Big integers are ordered against floats exactly, as == compares
+		// them, since rounding one to a float can land it on the other.
+		_, okLeftBig := left.(*big.Int)
+		_, okRightBig := right.(*big.Int)
+		if okLeftBig || okRightBig {
+			if result, ok := orderExact(tokenType, left, right); ok {
+				return result, nil
+			}
 		}
+		return i.floatOperation(operator, tokenType, leftNum, rightNum)
+	}
+
+	return nil, i.runtimeError(operator, "Unexpected values for operator: %s", operator.lexeme)
+}
+
+// orderExact applies a comparison operator to two numbers using
+// compareExact. It reports false for operators that aren't comparisons.
+func orderExact(tokenType int, left any, right any) (bool, bool) {
+	switch tokenType {
+	case TOKEN_GREATER, TOKEN_GREATER_EQUAL, TOKEN_LESS, TOKEN_LESS_EQUAL:
+	default:
+		return false, false
+	}
+
+	order, ok := compareExact(left, right)
+	if !ok {
+		// Nothing is ordered against NaN.
+		return false, true
+	}
+	switch tokenType {
+	case TOKEN_GREATER:
+		return order > 0, true
+	case TOKEN_GREATER_EQUAL:
+		return order >= 0, true
 	case TOKEN_LESS:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-		if okLeft && okRight {
-			return left < right, nil
-		}
+		return order < 0, true
+	}
+	return order <= 0, true
+}
+
+// integerOperation works on int64s while the result fits in one and hands
+// over to bigOperation when it would overflow.
+func (i *Interpreter) integerOperation(operator Token, tokenType int, left int64, right int64) (any, error) {
+	switch tokenType {
+	case TOKEN_GREATER:
+		return left > right, nil
+	case TOKEN_GREATER_EQUAL:
+		return left >= right, nil
+	case TOKEN_LESS:
+		return left < right, nil
 	case TOKEN_LESS_EQUAL:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-		if okLeft && okRight {
-			return left <= right, nil
+		return left <= right, nil
+	case TOKEN_PLUS:
+		if sum, ok := addInt64(left, right); ok {
+			return sum, nil
 		}
 	case TOKEN_MINUS:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-		if okLeft && okRight {
-			return left - right, nil
+		if difference, ok := subInt64(left, right); ok {
+			return difference, nil
+		}
+	case TOKEN_STAR:
+		if product, ok := mulInt64(left, right); ok {
+			return product, nil
 		}
 	case TOKEN_SLASH:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
-
 		if right == 0 {
-			return fmt.Errorf(
-				"[line: %d, col: %d] Division by zero",
-				expr.operator.operator.line,
-				expr.operator.operator.col,
-			), nil
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		return float64(left) / float64(right), nil
+	case TOKEN_TILDE_SLASH:
+		if right == 0 {
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		if left != math.MinInt64 || right != -1 {
+			return floorDiv(left, right), nil
+		}
+	case TOKEN_PERCENT:
+		if right == 0 {
+			return nil, i.runtimeError(operator, "Modulo by zero")
+		}
+		return floorMod(left, right), nil
+	case TOKEN_STAR_STAR:
+		// Powers overflow so easily that they are always worked out as big
+		// integers and shrunk back afterwards.
+	case TOKEN_AMPERSAND:
+		return left & right, nil
+	case TOKEN_PIPE:
+		return left | right, nil
+	case TOKEN_CARET:
+		return left ^ right, nil
+	case TOKEN_LESS_LESS:
+		if right < 0 {
+			return nil, i.runtimeError(operator, "Negative shift count %d", right)
 		}
+		if shifted := left << right; right < 63 && shifted>>right == left {
+			return shifted, nil
+		}
+	case TOKEN_GREATER_GREATER:
+		if right < 0 {
+			return nil, i.runtimeError(operator, "Negative shift count %d", right)
+		}
+		return left >> right, nil
+	default:
+		return nil, i.runtimeError(operator, "Unknown operator: %s", operator.lexeme)
+	}
+
+	return i.bigOperation(operator, tokenType, big.NewInt(left), big.NewInt(right))
+}
+
+// MAX_SHIFT bounds the shift counts and exponents of big integers so that
+// a typo can't exhaust memory.
+const MAX_SHIFT = 1 << 24
 
-		if okLeft && okRight {
-			return left / right, nil
+func (i *Interpreter) bigOperation(operator Token, tokenType int, left *big.Int, right *big.Int) (any, error) {
+	switch tokenType {
+	case TOKEN_GREATER:
+		return left.Cmp(right) > 0, nil
+	case TOKEN_GREATER_EQUAL:
+		return left.Cmp(right) >= 0, nil
+	case TOKEN_LESS:
+		return left.Cmp(right) < 0, nil
+	case TOKEN_LESS_EQUAL:
+		return left.Cmp(right) <= 0, nil
+	case TOKEN_PLUS:
+		return normalizeInt(new(big.Int).Add(left, right)), nil
+	case TOKEN_MINUS:
+		return normalizeInt(new(big.Int).Sub(left, right)), nil
+	case TOKEN_STAR:
+		return normalizeInt(new(big.Int).Mul(left, right)), nil
+	case TOKEN_SLASH:
+		if right.Sign() == 0 {
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		quotient, _ := new(big.Rat).SetFrac(left, right).Float64()
+		return quotient, nil
+	case TOKEN_TILDE_SLASH:
+		if right.Sign() == 0 {
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		quotient, _ := bigFloorDivMod(left, right)
+		return normalizeInt(quotient), nil
+	case TOKEN_PERCENT:
+		if right.Sign() == 0 {
+			return nil, i.runtimeError(operator, "Modulo by zero")
+		}
+		_, remainder := bigFloorDivMod(left, right)
+		return normalizeInt(remainder), nil
+	case TOKEN_STAR_STAR:
+		// A negative exponent can't give a whole number.
+		if right.Sign() < 0 {
+			leftNum, _ := toFloat(left)
+			rightNum, _ := toFloat(right)
+			return math.Pow(leftNum, rightNum), nil
+		}
+		if right.Cmp(big.NewInt(MAX_SHIFT)) > 0 && left.CmpAbs(big.NewInt(1)) > 0 {
+			return nil, i.runtimeError(operator, "Exponent %s is too large", right)
+		}
+		return normalizeInt(new(big.Int).Exp(left, right, nil)), nil
+	case TOKEN_AMPERSAND:
+		return normalizeInt(new(big.Int).And(left, right)), nil
+	case TOKEN_PIPE:
+		return normalizeInt(new(big.Int).Or(left, right)), nil
+	case TOKEN_CARET:
+		return normalizeInt(new(big.Int).Xor(left, right)), nil
+	case TOKEN_LESS_LESS, TOKEN_GREATER_GREATER:
+		if right.Sign() < 0 {
+			return nil, i.runtimeError(operator, "Negative shift count %s", right)
+		}
+		if tokenType == TOKEN_GREATER_GREATER {
+			// Shifting right by more than the bits there are leaves 0 or -1.
+			count := uint(left.BitLen() + 1)
+			if right.IsUint64() && right.Uint64() < uint64(count) {
+				count = uint(right.Uint64())
+			}
+			return normalizeInt(new(big.Int).Rsh(left, count)), nil
 		}
+		if right.Cmp(big.NewInt(MAX_SHIFT)) > 0 {
+			return nil, i.runtimeError(operator, "Shift count %s is too large", right)
+		}
+		return normalizeInt(new(big.Int).Lsh(left, uint(right.Int64()))), nil
+	}
+
+	return nil, i.runtimeError(operator, "Unknown operator: %s", operator.lexeme)
+}
+
+func (i *Interpreter) floatOperation(operator Token, tokenType int, left float64, right float64) (any, error) {
+	switch tokenType {
+	case TOKEN_GREATER:
+		return left > right, nil
+	case TOKEN_GREATER_EQUAL:
+		return left >= right, nil
+	case TOKEN_LESS:
+		return left < right, nil
+	case TOKEN_LESS_EQUAL:
+		return left <= right, nil
+	case TOKEN_PLUS:
+		return left + right, nil
+	case TOKEN_MINUS:
+		return left - right, nil
 	case TOKEN_STAR:
-		left, okLeft := left.(float64)
-		right, okRight := right.(float64)
+		return left * right, nil
+	case TOKEN_SLASH:
+		if right == 0 {
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		return left / right, nil
+	case TOKEN_TILDE_SLASH:
+		if right == 0 {
+			return nil, i.runtimeError(operator, "Division by zero")
+		}
+		return math.Floor(left / right), nil
+	case TOKEN_PERCENT:
+		// The result takes the sign of the divisor, matching floor division.
+		if right == 0 {
+			return nil, i.runtimeError(operator, "Modulo by zero")
+		}
+		return left - right*math.Floor(left/right), nil
+	case TOKEN_STAR_STAR:
+		return math.Pow(left, right), nil
+	case TOKEN_AMPERSAND, TOKEN_PIPE, TOKEN_CARET, TOKEN_LESS_LESS, TOKEN_GREATER_GREATER:
+		return nil, i.runtimeError(operator, "Operands of '%s' must be integers.", operator.lexeme)
+	}
+
+	return nil, i.runtimeError(operator, "Unknown operator: %s", operator.lexeme)
+}
 
-		if okLeft && okRight {
-			return left * right, nil
+func (i *Interpreter) visitLogical(expr Logical) (any, error) {
+	left, err := i.evaluate(expr.left)
+	if err != nil {
+		return nil, err
+	}
+
+	// The operand that decides the result is returned as is rather than
+	// being coerced to a boolean.
+	if expr.operator.tokenType == TOKEN_QUESTION_QUESTION {
+		if left != nil {
+			return left, nil
 		}
-	case TOKEN_PLUS:
-		leftStr, okLeftStr := left.(string)
-		rightStr, okRightStr := right.(string)
-		leftNum, okNumLeftNum := left.(float64)
-		rightNum, okNumRightNum := right.(float64)
+	} else if expr.operator.tokenType == TOKEN_OR {
+		if isTruthy(left) {
+			return left, nil
+		}
+	} else {
+		if !isTruthy(left) {
+			return left, nil
+		}
+	}
 
-		if okLeftStr && okRightStr {
-			return leftStr + rightStr, nil
-		} else if okNumLeftNum && okNumRightNum {
-			return leftNum + rightNum, nil
+	return i.evaluate(expr.right)
+}
+
+func (i *Interpreter) visitCall(expr Call) (any, error) {
+	value, _, err := i.callLink(expr)
+	return value, err
+}
+
+func (i *Interpreter) callLink(expr Call) (any, bool, error) {
+	callee, skipped, err := i.evaluateLink(expr.callee)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+
+	var arguments []any
+	for _, argument := range expr.arguments {
+		value, err := i.evaluate(argument)
+		if err != nil {
+			return nil, false, err
 		}
-	default:
-		return fmt.Errorf(
-			"[line: %d, col: %d] Unknown operator: %s",
-			expr.operator.operator.line,
-			expr.operator.operator.col,
-			expr.operator.operator.lexeme,
-		), nil
+		arguments = append(arguments, value)
+	}
+
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		return nil, false, i.runtimeError(expr.paren, "Can only call functions and classes.")
+	}
+
+	if len(arguments) != function.arity() {
+		return nil, false, i.runtimeError(expr.paren, "Expected %d arguments but got %d.", function.arity(), len(arguments))
+	}
+
+	if i.calls >= MAX_FRAMES {
+		return nil, false, i.runtimeError(expr.paren, "Stack overflow.")
+	}
+	i.calls++
+	i.pushFrame(fmt.Sprintf("call to %v", function), expr.paren)
+	defer func() {
+		i.popFrame()
+		i.calls--
+	}()
+
+	value, err := function.call(i, arguments)
+	return value, false, err
+}
+
+func (i *Interpreter) visitGet(expr Get) (any, error) {
+	value, _, err := i.getLink(expr)
+	return value, err
+}
+
+func (i *Interpreter) getLink(expr Get) (any, bool, error) {
+	object, skipped, err := i.evaluateLink(expr.object)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if object == nil && expr.optional {
+		return nil, true, nil
+	}
+
+	if instance, ok := object.(*LoxInstance); ok {
+		if value, ok := instance.get(expr.name.lexeme); ok {
+			return value, false, nil
+		}
+		return nil, false, i.runtimeError(expr.name, "Undefined property '%s'.", expr.name.lexeme)
+	}
+
+	if module, ok := object.(*LoxModule); ok {
+		if value, ok := module.get(expr.name.lexeme); ok {
+			return value, false, nil
+		}
+		return nil, false, i.runtimeError(expr.name, "Module '%s' has no export named '%s'.", module.name, expr.name.lexeme)
+	}
+
+	return nil, false, i.runtimeError(expr.name, "Only instances and modules have properties.")
+}
+
+// evaluateLink evaluates the object of a Get or Index, or the callee of a
+// Call. skipped reports that a "?." further down the same chain of accesses
+// found nil, in which case the rest of the chain is skipped and gives nil.
+// Parentheses end a chain.
+func (i *Interpreter) evaluateLink(expr Expr) (any, bool, error) {
+	switch expr := expr.(type) {
+	case Call:
+		return i.callLink(expr)
+	case Get:
+		return i.getLink(expr)
+	case Index:
+		return i.indexLink(expr)
+	}
+	value, err := i.evaluate(expr)
+	return value, false, err
+}
+
+func (i *Interpreter) visitSet(expr Set) (any, error) {
+	object, err := i.evaluate(expr.object)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		return nil, i.runtimeError(expr.name, "Only instances have fields.")
+	}
+
+	value, err := i.evaluate(expr.value)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf(
-		"[line: %d, col: %d] Unexpected values for operator: %s",
-		expr.operator.operator.line,
-		expr.operator.operator.col,
-		expr.operator.operator.lexeme,
-	), nil
+	instance.set(expr.name.lexeme, value)
+	return value, nil
+}
+
+func (i *Interpreter) visitThis(expr This) (any, error) {
+	return i.lookUpVariable(expr.keyword, *expr.depth)
+}
+
+func (i *Interpreter) visitSuper(expr Super) (any, error) {
+	value, err := i.environment.getAt(*expr.depth, "super")
+	if err != nil {
+		return nil, err
+	}
+	superclass, ok := value.(*LoxClass)
+	if !ok {
+		return nil, i.runtimeError(expr.keyword, "'super' does not refer to a class.")
+	}
+
+	// "this" always lives in the scope just inside the one holding "super".
+	value, err = i.environment.getAt(*expr.depth-1, "this")
+	if err != nil {
+		return nil, err
+	}
+	instance, ok := value.(*LoxInstance)
+	if !ok {
+		return nil, i.runtimeError(expr.keyword, "'super' used without an instance.")
+	}
+
+	method, ok := superclass.findMethod(expr.method.lexeme)
+	if !ok {
+		return nil, i.runtimeError(expr.method, "Undefined property '%s'.", expr.method.lexeme)
+	}
+
+	return method.bind(instance), nil
+}
+
+func (i *Interpreter) visitInterpolation(expr Interpolation) (any, error) {
+	var out strings.Builder
+	for index, hole := range expr.exprs {
+		out.WriteString(expr.parts[index])
+		value, err := i.evaluate(hole)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString(stringify(value))
+	}
+	out.WriteString(expr.parts[len(expr.parts)-1])
+
+	return out.String(), nil
+}
+
+func (i *Interpreter) visitListLiteral(expr ListLiteral) (any, error) {
+	elements := make([]any, 0, len(expr.elements))
+	for _, element := range expr.elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+
+	return NewLoxList(elements), nil
+}
+
+func (i *Interpreter) visitMapLiteral(expr MapLiteral) (any, error) {
+	m := NewLoxMap()
+	for index, keyExpr := range expr.keys {
+		key, err := i.evaluate(keyExpr)
+		if err != nil {
+			return nil, err
+		}
+		value, err := i.evaluate(expr.values[index])
+		if err != nil {
+			return nil, err
+		}
+		if !m.set(key, value) {
+			return nil, i.runtimeError(expr.brace, "Map keys must be nil, booleans, numbers or strings, got %s.", inspect(key))
+		}
+	}
+
+	return m, nil
+}
+
+func (i *Interpreter) visitIndex(expr Index) (any, error) {
+	value, _, err := i.indexLink(expr)
+	return value, err
+}
+
+func (i *Interpreter) indexLink(expr Index) (any, bool, error) {
+	object, skipped, err := i.evaluateLink(expr.object)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if object == nil && expr.optional {
+		return nil, true, nil
+	}
+	index, err := i.evaluate(expr.index)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := i.getIndex(expr.bracket, object, index)
+	return value, false, err
+}
+
+func (i *Interpreter) getIndex(bracket Token, object any, index any) (any, error) {
+	// Looking up a key that isn't in a map gives nil.
+	if m, ok := object.(*LoxMap); ok {
+		if _, ok := hashKey(index); !ok {
+			return nil, i.runtimeError(bracket, "Map keys must be nil, booleans, numbers or strings, got %s.", inspect(index))
+		}
+		value, _ := m.get(index)
+		return value, nil
+	}
+
+	list, ok := object.(*LoxList)
+	if !ok {
+		return nil, i.runtimeError(bracket, "Only lists and maps can be indexed.")
+	}
+
+	position, ok := listIndex(list, index)
+	if !ok {
+		return nil, i.runtimeError(bracket, "List index %s out of range for list of length %d.", inspect(index), len(list.elements))
+	}
+
+	return list.elements[position], nil
+}
+
+func (i *Interpreter) visitSetIndex(expr SetIndex) (any, error) {
+	object, err := i.evaluate(expr.object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(expr.index)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.evaluate(expr.value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.setIndex(expr.bracket, object, index, value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (i *Interpreter) setIndex(bracket Token, object any, index any, value any) error {
+	if m, ok := object.(*LoxMap); ok {
+		if !m.set(index, value) {
+			return i.runtimeError(bracket, "Map keys must be nil, booleans, numbers or strings, got %s.", inspect(index))
+		}
+		return nil
+	}
+
+	list, ok := object.(*LoxList)
+	if !ok {
+		return i.runtimeError(bracket, "Only list elements and map entries can be assigned by index.")
+	}
+
+	position, ok := listIndex(list, index)
+	if !ok {
+		return i.runtimeError(bracket, "List index %s out of range for list of length %d.", inspect(index), len(list.elements))
+	}
+
+	list.elements[position] = value
+	return nil
+}
+
+func (i *Interpreter) visitMatch(expr Match) (any, error) {
+	subject, err := i.evaluate(expr.subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.arms {
+		// Each arm binds its names in an environment of its own, so a failed
+		// match leaves nothing behind.
+		env := NewEnvironment("MATCHENV", i.environment)
+		if !matchPattern(arm.pattern, subject, env) {
+			continue
+		}
+		value, matched, err := i.evaluateArm(arm, env)
+		if err != nil || matched {
+			return value, err
+		}
+	}
+
+	return nil, i.runtimeError(expr.keyword, "No match arm matched %s.", inspect(subject))
+}
+
+// evaluateArm checks the guard of an arm whose pattern matched and, if it
+// passes, evaluates the body inside env.
+func (i *Interpreter) evaluateArm(arm MatchArm, env *Environment) (any, bool, error) {
+	previousEnv := i.environment
+	i.environment = env
+	defer func() { i.environment = previousEnv }()
+
+	if arm.guard != nil {
+		guard, err := i.evaluate(arm.guard)
+		if err != nil || !isTruthy(guard) {
+			return nil, false, err
+		}
+	}
+
+	value, err := i.evaluate(arm.body)
+	return value, true, err
 }
 
 func (i *Interpreter) visitOperator(expr Operator) (any, error) {
@@ -279,6 +1274,34 @@ func (i *Interpreter) visitTernary(expr Ternary) (any, error) {
 	}
 }
 
+// MAX_FRAMES bounds how deeply calls can nest, so that runaway recursion is
+// reported as a stack overflow long before Go's own stack runs out. Blocks
+// don't count, so a recursive function gets the same depth however many
+// blocks its body nests.
+const MAX_FRAMES = 10000
+
+func (i *Interpreter) pushFrame(name string, token Token) {
+	i.frames = append(i.frames, Frame{name: name, token: token})
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// callError builds a RuntimeError pointing at the call currently being
+// executed. Native functions use it since they have no token of their own.
+func (i *Interpreter) callError(format string, args ...any) *RuntimeError {
+	return i.runtimeError(i.frames[len(i.frames)-1].token, format, args...)
+}
+
+// runtimeError builds a RuntimeError at token carrying a snapshot of the
+// currently active frames.
+func (i *Interpreter) runtimeError(token Token, format string, args ...any) *RuntimeError {
+	stack := make([]Frame, len(i.frames))
+	copy(stack, i.frames)
+	return &RuntimeError{token: token, message: fmt.Sprintf(format, args...), stack: stack}
+}
+
 func (i *Interpreter) evaluate(expr Expr) (any, error) {
 	return expr.accept(i)
 }
@@ -0,0 +1,147 @@
+package main
+
+import "testing"
+
+func TestIf(t *testing.T) {
+	runCases(t, []loxCase{
+		{"then branch", `if (true) print "then"; else print "else";`, "then\n"},
+		{"else branch", `if (false) print "then"; else print "else";`, "else\n"},
+		{"no else", `if (false) print "then"; print "after";`, "after\n"},
+		{"truthiness", `if (nil) print "nil"; else if (0) print "zero";`, "zero\n"},
+		{"dangling else binds to nearest if", `if (true) if (false) print "inner"; else print "else";`, "else\n"},
+		{"dangling else skipped with outer", `if (false) if (true) print "inner"; else print "else"; print "done";`, "done\n"},
+		{"block branches", `if (1 < 2) { print "a"; print "b"; } else { print "c"; }`, "a\nb\n"},
+		{"missing paren", `if true print "x";`,
+			"error: at 'true': Expected '(' after 'if'.\n  --> test.lox:1:4\n  |\n1 | if true print \"x\";\n  |    ^~~~\n"},
+		{"missing condition close", `if (true print "x";`,
+			"error: at 'print': Expected ')' after if condition.\n  --> test.lox:1:10\n  |\n1 | if (true print \"x\";\n  |          ^~~~~\n"},
+	})
+}
+
+func TestLoops(t *testing.T) {
+	runCases(t, []loxCase{
+		{"while", `var i = 0; while (i < 3) i = i + 1; print i;`, "3\n"},
+		{"while never runs", `while (false) print "body"; print "done";`, "done\n"},
+		{"for", `for (var i = 0; i < 3; i = i + 1) print i;`, "0\n1\n2\n"},
+		{"for with only a condition", `var i = 0; for (; i < 2;) i = i + 1; print i;`, "2\n"},
+		{"for with expression initializer", `var i; for (i = 5; i < 7; i = i + 1) print i;`, "5\n6\n"},
+		{"for variable is scoped to the loop", `for (var i = 0; i < 1; i = i + 1) print i; print i;`,
+			"0\n" +
+				"error: Undefined variable : i\n  --> test.lox:1:50\n  |\n1 | for (var i = 0; i < 1; i = i + 1) print i; print i;\n  |                                                  ^\n"},
+		{"missing while paren", `while true print "x";`,
+			"error: at 'true': Expected '(' after 'while'.\n  --> test.lox:1:7\n  |\n1 | while true print \"x\";\n  |       ^~~~\n"},
+		{"missing loop condition semicolon", `for (var i = 0; i < 3 i = i + 1) print i;`,
+			"error: at 'i': Expected ';' after loop condition.\n  --> test.lox:1:23\n  |\n1 | for (var i = 0; i < 3 i = i + 1) print i;\n  |                       ^\n"},
+		{"missing for close paren", `for (var i = 0; i < 1; i = i + 1 print i;`,
+			"error: at 'print': Expected ')' after for clauses.\n  --> test.lox:1:34\n  |\n1 | for (var i = 0; i < 1; i = i + 1 print i;\n  |                                  ^~~~~\n"},
+	})
+}
+
+func TestLogical(t *testing.T) {
+	runCases(t, []loxCase{
+		{"or returns the first truthy operand", `print nil or "yes";`, "yes\n"},
+		{"or keeps a truthy left operand", `print 1 or 2;`, "1\n"},
+		{"and returns the first falsey operand", `print nil and "no";`, "nil\n"},
+		{"and returns the right operand", `print 1 and "two";`, "two\n"},
+		{"and binds tighter than or", `print false and false or "or";`, "or\n"},
+		{"lower than equality", `print 1 == 1 and 2 == 2;`, "true\n"},
+		{"or short-circuits", `print true or missing;`, "true\n"},
+		{"and short-circuits", `print false and missing;`, "false\n"},
+		{"right operand runs when needed", `print false or missing;`,
+			"error: Undefined variable : missing\n  --> test.lox:1:16\n  |\n1 | print false or missing;\n  |                ^~~~~~~\n"},
+		{"missing right operand", `print true and;`,
+			"error: at ';': Expected expression.\n  --> test.lox:1:15\n  |\n1 | print true and;\n  |               ^\n"},
+	})
+}
+
+func TestBreakContinue(t *testing.T) {
+	runCases(t, []loxCase{
+		{"break", `for (var i = 0; i < 5; i = i + 1) { if (i == 2) break; print i; }`, "0\n1\n"},
+		{"continue runs the increment", `for (var i = 0; i < 4; i = i + 1) { if (i == 1) continue; print i; }`,
+			"0\n2\n3\n"},
+		{"break in while", `while (true) { print "once"; break; } print "after";`, "once\nafter\n"},
+		{"break leaves the innermost loop", `for (var i = 0; i < 2; i = i + 1) for (var j = 0; j < 5; j = j + 1) { if (j == 1) break; print i; }`,
+			"0\n1\n"},
+		{"labelled continue", `outer: for (var i = 0; i < 3; i = i + 1) for (var j = 0; j < 3; j = j + 1) { if (j == 1) continue outer; print i; }`,
+			"0\n1\n2\n"},
+		{"labelled break", `outer: for (var i = 0; i < 3; i = i + 1) for (var j = 0; j < 3; j = j + 1) { if (i == 1) break outer; print j; } print "done";`,
+			"0\n1\n2\ndone\n"},
+		{"break outside a loop", `break;`,
+			"error: at 'break': Can't use 'break' outside of a loop.\n  --> test.lox:1:1\n  |\n1 | break;\n  | ^~~~~\n"},
+		{"continue outside a loop", `if (true) continue;`,
+			"error: at 'continue': Can't use 'continue' outside of a loop.\n  --> test.lox:1:11\n  |\n1 | if (true) continue;\n  |           ^~~~~~~~\n"},
+		{"unknown label", `while (true) break outer;`,
+			"error: at 'outer': No enclosing loop is labelled 'outer'.\n  --> test.lox:1:20\n  |\n1 | while (true) break outer;\n  |                    ^~~~~\n"},
+		{"label reused by an enclosing loop", `outer: while (true) outer: while (true) break;`,
+			"error: at 'outer': Label 'outer' is already used by an enclosing loop.\n  --> test.lox:1:21\n  |\n1 | outer: while (true) outer: while (true) break;\n  |                     ^~~~~\n"},
+		{"label without a loop", `outer: print 1;`,
+			"error: at 'print': Expected a loop after label.\n  --> test.lox:1:8\n  |\n1 | outer: print 1;\n  |        ^~~~~\n"},
+	})
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	runCases(t, []loxCase{
+		{"variables", `var x = 10; x += 5; x -= 3; x *= 2; x /= 4; print x;`, "6.0\n"},
+		{"strings", `var s = "a"; s += "b"; print s;`, "ab\n"},
+		{"prefix increment returns the new value", `var x = 1; print ++x; print x;`, "2\n2\n"},
+		{"postfix increment returns the old value", `var x = 1; print x++; print x;`, "1\n2\n"},
+		{"prefix and postfix decrement", `var x = 5; print --x; print x--; print x;`, "4\n4\n3\n"},
+		{"in a for loop", `for (var i = 0; i < 3; i++) print i;`, "0\n1\n2\n"},
+		{"properties", `class C {} var c = C(); c.n = 1; c.n += 2; c.n++; print c.n;`, "4\n"},
+		{"list elements", `var l = [1, 2]; l[1] *= 10; l[0]--; print l;`, "[0, 20]\n"},
+		{"map entries", `var m = {"a": 1}; m["a"] += 1; print m;`, "{\"a\": 2}\n"},
+		{"target is evaluated once", `var calls = 0; fun at() { calls++; return 0; } var l = [1];
+l[at()] += 5; l[at()]++; print l; print calls;`,
+			"[7]\n2\n"},
+		{"locals and closures", `fun counter() { var n = 0; fun next() { return ++n; } return next; }
+var c = counter(); c(); print c();`,
+			"2\n"},
+		{"invalid target", `1 += 2;`,
+			"error: at '+=': Invalid assignment target.\n  --> test.lox:1:3\n  |\n1 | 1 += 2;\n  |   ^~\n"},
+		{"invalid increment operand", `++1;`,
+			"error: at '++': Invalid operand for '++'.\n  --> test.lox:1:1\n  |\n1 | ++1;\n  | ^~\n"},
+		{"invalid postfix operand", `(1)--;`,
+			"error: at '--': Invalid operand for '--'.\n  --> test.lox:1:4\n  |\n1 | (1)--;\n  |    ^~\n"},
+		{"undefined variable", `missing += 1;`,
+			"error: Undefined variable : missing\n  --> test.lox:1:1\n  |\n1 | missing += 1;\n  | ^~~~~~~\n"},
+		{"mismatched operands", `var x = "a"; x -= 1;`,
+			"error: Unexpected values for operator: -=\n  --> test.lox:1:16\n  |\n1 | var x = \"a\"; x -= 1;\n  |                ^~\n"},
+		{"increment a string", `var s = "a"; s++;`,
+			"error: Unexpected values for operator: ++\n  --> test.lox:1:15\n  |\n1 | var s = \"a\"; s++;\n  |               ^~\n"},
+		{"property of a non-instance", `var n = 1; n.x += 1;`,
+			"error: Only instances have fields.\n  --> test.lox:1:14\n  |\n1 | var n = 1; n.x += 1;\n  |              ^\n"},
+		{"divide by zero", `var x = 1; x /= 0;`,
+			"error: Division by zero\n  --> test.lox:1:14\n  |\n1 | var x = 1; x /= 0;\n  |              ^~\n"},
+	})
+}
+
+func TestOptionalChaining(t *testing.T) {
+	runCases(t, []loxCase{
+		{"coalesce", `print nil ?? "default"; print false ?? "default";`, "default\nfalse\n"},
+		{"coalesce short-circuits", `print 1 ?? missing;`, "1\n"},
+		{"coalesce chains", `print nil ?? nil ?? "last";`, "last\n"},
+		{"optional get", "var a = nil; print a?.b;", "nil\n"},
+		{"optional get on an instance", `class P {} var p = P(); p.b = "b"; print p?.b;`, "b\n"},
+		{"optional method call", `class P { m() { return "m"; } } var p = P(); print p?.m();`, "m\n"},
+		{"optional index", "var l = nil; print l?.[0];", "nil\n"},
+		{"optional index on a value", "var l = [1, 2]; print l?.[1];", "2\n"},
+		{"rest of chain is skipped", "var a = nil; print a?.b.c;", "nil\n"},
+		{"calls are skipped", "var a = nil; print a?.b();", "nil\n"},
+		{"arguments are not evaluated", `var a = nil; fun loud() { print "evaluated"; } print a?.b(loud());`,
+			"nil\n"},
+		{"index after a nil is skipped", "var l = nil; print l?.[0][1];", "nil\n"},
+		{"parentheses end the chain", "var a = nil; print (a?.b).c;",
+			"error: Only instances and modules have properties.\n  --> test.lox:1:27\n  |\n1 | var a = nil; print (a?.b).c;\n  |                           ^\n"},
+		{"nil found later in the chain", "class P {} var p = P(); p.q = nil; print p?.q.r;",
+			"error: Only instances and modules have properties.\n  --> test.lox:1:47\n  |\n1 | class P {} var p = P(); p.q = nil; print p?.q.r;\n  |                                               ^\n"},
+		{"optional index chains", "var l = [[1, 2]]; print l?.[0]?.[1];", "2\n"},
+		{"ternary with lists", "var c = true; print c?[1]:[2];", "[1]\n"},
+		{"ternary is unaffected", `var c = true; print c ? "yes" : "no";`, "yes\n"},
+		{"optional get on a non-instance", `var n = 1; print n?.b;`,
+			"error: Only instances and modules have properties.\n  --> test.lox:1:21\n  |\n1 | var n = 1; print n?.b;\n  |                     ^\n"},
+		{"undefined property is still an error", `class P {} print P()?.missing;`,
+			"error: Undefined property 'missing'.\n  --> test.lox:1:23\n  |\n1 | class P {} print P()?.missing;\n  |                       ^~~~~~~\n"},
+		{"optional index out of range", "var l = [1]; print l?.[3];",
+			"error: List index 3 out of range for list of length 1.\n  --> test.lox:1:23\n  |\n1 | var l = [1]; print l?.[3];\n  |                       ^\n"},
+	})
+}
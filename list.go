@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// LoxList is the runtime value of a list. It is always handled by pointer so
+// that every reference sees changes made through any other.
+type LoxList struct {
+	elements []any
+}
+
+func NewLoxList(elements []any) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+func (l *LoxList) String() string {
+	return l.inspect(make(map[any]bool))
+}
+
+func (l *LoxList) inspect(seen map[any]bool) string {
+	if seen[l] {
+		return "[...]"
+	}
+	seen[l] = true
+	defer delete(seen, l)
+
+	parts := make([]string, len(l.elements))
+	for i, element := range l.elements {
+		parts[i] = inspectNested(element, seen)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// listIndex checks that index is an integer, or a whole float, addressing
+// one of the elements of list and converts it to a position.
+func listIndex(list *LoxList, index any) (int, bool) {
+	var position int64
+	switch index := index.(type) {
+	case int64:
+		position = index
+	case float64:
+		if index != math.Trunc(index) || math.Abs(index) > float64(len(list.elements)) {
+			return 0, false
+		}
+		position = int64(index)
+	default:
+		return 0, false
+	}
+
+	if position < 0 || position >= int64(len(list.elements)) {
+		return 0, false
+	}
+	return int(position), true
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestLists(t *testing.T) {
+	runCases(t, []loxCase{
+		{"literal", `print [1, "two", nil, [3]];`, "[1, \"two\", nil, [3]]\n"},
+		{"empty", `print [];`, "[]\n"},
+		{"trailing expression is not a comma operator", `print [(1, 2), 3];`, "[2, 3]\n"},
+		{"index", "var l = [1, 2, 3]; print l[0] + l[2];", "4\n"},
+		{"whole float index", "var l = [1, 2]; print l[1.0];", "2\n"},
+		{"set index", "var l = [1, 2]; l[1] = 5; print l;", "[1, 5]\n"},
+		{"nested set index", "var l = [[1], [2]]; l[1][0] = 3; print l;", "[[1], [3]]\n"},
+		{"append and pop", "var l = []; append(l, 1); append(l, 2); print pop(l); print l;", "2\n[1]\n"},
+		{"len", `print len([1, 2, 3]);`, "3\n"},
+		{"len of a string", `print len("four");`, "4\n"},
+		{"shared by reference", "var a = [1]; var b = a; append(b, 2); print a;", "[1, 2]\n"},
+		{"contains itself", "var l = [1]; append(l, l); print l;", "[1, [...]]\n"},
+		{"contains itself twice", "var l = []; append(l, l); append(l, l); print l;", "[[...], [...]]\n"},
+		{"same list twice", "var a = [1]; print [a, a];", "[[1], [1]]\n"},
+		{"nested self reference", "var a = [1]; var b = [a]; append(a, b); print a;", "[1, [[...]]]\n"},
+		{"out of range", "print [1][3];",
+			"error: List index 3 out of range for list of length 1.\n  --> test.lox:1:10\n  |\n1 | print [1][3];\n  |          ^\n"},
+		{"negative index", "print [1][-1];",
+			"error: List index -1 out of range for list of length 1.\n  --> test.lox:1:10\n  |\n1 | print [1][-1];\n  |          ^\n"},
+		{"fractional index", "print [1, 2][0.5];",
+			"error: List index 0.5 out of range for list of length 2.\n  --> test.lox:1:13\n  |\n1 | print [1, 2][0.5];\n  |             ^\n"},
+		{"index is not a number", `print [1]["0"];`,
+			"error: List index \"0\" out of range for list of length 1.\n  --> test.lox:1:10\n  |\n1 | print [1][\"0\"];\n  |          ^\n"},
+		{"index a non-list", `var n = 1; print n[0];`,
+			"error: Only lists and maps can be indexed.\n  --> test.lox:1:19\n  |\n1 | var n = 1; print n[0];\n  |                   ^\n"},
+		{"set out of range", "var l = []; l[0] = 1;",
+			"error: List index 0 out of range for list of length 0.\n  --> test.lox:1:14\n  |\n1 | var l = []; l[0] = 1;\n  |              ^\n"},
+		{"set on a non-list", `var s = "str"; s[0] = "S";`,
+			"error: Only list elements and map entries can be assigned by index.\n  --> test.lox:1:17\n  |\n1 | var s = \"str\"; s[0] = \"S\";\n  |                 ^\n"},
+		{"pop an empty list", "pop([]);",
+			"error: Can't pop from an empty list.\n  --> test.lox:1:7\n  |\n1 | pop([]);\n  |       ^\n  = in call to <native fn pop> at test.lox:1:7\n"},
+		{"append to a non-list", "append(1, 2);",
+			"error: Can only append to a list.\n  --> test.lox:1:12\n  |\n1 | append(1, 2);\n  |            ^\n  = in call to <native fn append> at test.lox:1:12\n"},
+		{"built-in arity", "len();",
+			"error: Expected 1 arguments but got 0.\n  --> test.lox:1:5\n  |\n1 | len();\n  |     ^\n"},
+		{"missing closing bracket", "print [1, 2;",
+			"error: at ';': Expected ']' after list elements.\n  --> test.lox:1:12\n  |\n1 | print [1, 2;\n  |            ^\n"},
+	})
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -13,6 +15,9 @@ var showTokens bool = true
 var showAst bool = true
 var showSource bool = false
 
+// REPL_FILE is the file name given to code typed at the prompt.
+const REPL_FILE = "<stdin>"
+
 func main() {
 	if len(os.Args) > 2 {
 		fmt.Println("usage: glox [file]")
@@ -31,11 +36,22 @@ func runFile(path string) {
 	}
 	defer f.Close()
 	source, err := io.ReadAll(f)
-	run(string(source), nil)
+
+	// The main file is marked as loading so that a module importing it back
+	// is reported as a cycle.
+	loader := NewModuleLoader()
+	if abs, err := filepath.Abs(path); err == nil {
+		loader.enter(abs)
+	}
+	run(path, string(source), nil, nil, loader)
 }
 
 func runPrompt() {
 	env := make(map[string]any)
+	// Which globals are constants is kept apart from their values, and
+	// carried from line to line along with them.
+	constants := make(map[string]bool)
+	loader := NewModuleLoader()
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("> ")
@@ -87,20 +103,16 @@ func runPrompt() {
 			continue
 		}
 
-		result := run(source, &env)
+		result := run(REPL_FILE, source, &env, constants, loader)
 		if result == nil {
 			continue
 		}
 
-		if strResult, ok := result.(string); ok {
-			fmt.Printf("\"%s\"\n", strResult)
-		} else {
-			fmt.Printf("%v\n", result)
-		}
+		fmt.Println(inspect(result))
 	}
 }
 
-func run(source string, env *map[string]any) any {
+func run(file string, source string, env *map[string]any, constants map[string]bool, loader *ModuleLoader) any {
 
 	if !strings.HasSuffix(source, ";") && !strings.HasSuffix(source, "}") {
 		source += ";"
@@ -110,28 +122,15 @@ func run(source string, env *map[string]any) any {
 		fmt.Print(source)
 	}
 
-	//scan
-	scanner := NewGloxScanner(source, reportErrorScan)
-	tokens := scanner.ScanTokens()
-	if hadError {
-		hadError = false
-		return nil
-	}
-	if showTokens {
-		fmt.Println(tokens)
-	}
-
-	//parse
-	parser := NewParser(tokens, reportErrorParse)
-	stmts, err := parser.parse()
-	if err != nil {
-		fmt.Println("Error parsing expression: ", err)
+	diagnostics := NewDiagnostics(file, source)
+	stmts, ok := compile(diagnostics, source)
+	if !ok {
 		return nil
 	}
 
 	if showAst {
 		astPrinter := NewAstPrinter(env)
-		err = astPrinter.print(stmts)
+		err := astPrinter.print(stmts)
 		if err != nil {
 			fmt.Println("Error printing tree: ", err)
 			return nil
@@ -139,11 +138,49 @@ func run(source string, env *map[string]any) any {
 	}
 
 	// run
-	interp := NewInterpreter(env)
+	interp := NewInterpreter(file, env, constants, loader)
 	val, err := interp.interpert(stmts)
 	if err != nil {
-		fmt.Println("Error interpreting: ", err)
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			diagnostics.reportRuntime(runtimeErr)
+		} else {
+			fmt.Fprintln(os.Stderr, "Error interpreting: ", err)
+		}
 		return nil
 	}
 	return val
 }
+
+// compile scans, parses and resolves source, reporting any errors through
+// diagnostics. It is shared by the main program and imported modules.
+func compile(diagnostics *Diagnostics, source string) ([]Statement, bool) {
+	//scan
+	scanner := NewGloxScanner(source, diagnostics.reportScan)
+	tokens := scanner.ScanTokens()
+	if hadError {
+		hadError = false
+		return nil, false
+	}
+	if showTokens {
+		fmt.Println(tokens)
+	}
+
+	//parse
+	// Syntax and resolution errors have already been reported by the time
+	// they come back here.
+	parser := NewParser(tokens, diagnostics.reportParse, diagnostics.reportWarning)
+	stmts, err := parser.parse()
+	if err != nil {
+		return nil, false
+	}
+
+	//resolve
+	resolver := NewResolver(diagnostics.reportParse, diagnostics.reportNote)
+	err = resolver.resolve(stmts)
+	if err != nil {
+		return nil, false
+	}
+
+	return stmts, true
+}
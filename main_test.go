@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+// loxCase is a lox program together with everything it should print,
+// diagnostics included.
+type loxCase struct {
+	name   string
+	source string
+	want   string
+}
+
+// capture runs f with stdout and stderr sent to the same pipe and returns
+// what was written to them, in order.
+func capture(t *testing.T, f func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+
+	output := make(chan string)
+	go func() {
+		var buffer bytes.Buffer
+		io.Copy(&buffer, reader)
+		output <- buffer.String()
+	}()
+
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	}()
+	f()
+	writer.Close()
+	return <-output
+}
+
+// runLox runs source as a file of its own and returns its output.
+func runLox(t *testing.T, source string) string {
+	t.Helper()
+	showTokens, showAst = false, false
+	return capture(t, func() {
+		run("test.lox", source, nil, nil, NewModuleLoader())
+	})
+}
+
+func runCases(t *testing.T, cases []loxCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := runLox(t, c.source); got != c.want {
+				t.Errorf("got output\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
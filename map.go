@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+type mapEntry struct {
+	key   any
+	value any
+}
+
+// LoxMap is the runtime value of a map. Entries remember the order they were
+// inserted in so that printing and iterating over keys is predictable.
+type LoxMap struct {
+	entries map[any]mapEntry
+	order   []any
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{entries: make(map[any]mapEntry)}
+}
+
+// bigIntKey is the hash of an integer too large for an int64. Its decimal
+// digits are used since *big.Int values are pointers.
+type bigIntKey string
+
+// hashKey returns the Go value a lox value is stored under when used as a map
+// key. Only immutable values can be keys: nil, booleans, numbers and strings.
+// Whole floats hash as integers so that m[1] and m[1.0] are the same entry,
+// as 1 == 1.0.
+func hashKey(value any) (any, bool) {
+	switch value := value.(type) {
+	case float64:
+		if value != math.Trunc(value) || math.IsInf(value, 0) {
+			return value, true
+		}
+		if value >= math.MinInt64 && value < math.MaxInt64 {
+			return int64(value), true
+		}
+		whole, _ := new(big.Float).SetFloat64(value).Int(nil)
+		return bigIntKey(whole.String()), true
+	case *big.Int:
+		return bigIntKey(value.String()), true
+	case nil, bool, int64, string:
+		return value, true
+	}
+	return nil, false
+}
+
+func (m *LoxMap) get(key any) (any, bool) {
+	hash, ok := hashKey(key)
+	if !ok {
+		return nil, false
+	}
+	entry, ok := m.entries[hash]
+	return entry.value, ok
+}
+
+// set stores value under key, reporting false when key can't be hashed.
+func (m *LoxMap) set(key any, value any) bool {
+	hash, ok := hashKey(key)
+	if !ok {
+		return false
+	}
+
+	if entry, ok := m.entries[hash]; ok {
+		m.entries[hash] = mapEntry{key: entry.key, value: value}
+		return true
+	}
+
+	m.entries[hash] = mapEntry{key: key, value: value}
+	m.order = append(m.order, hash)
+	return true
+}
+
+func (m *LoxMap) delete(key any) bool {
+	hash, ok := hashKey(key)
+	if !ok {
+		return false
+	}
+	if _, ok := m.entries[hash]; !ok {
+		return false
+	}
+
+	delete(m.entries, hash)
+	for i, existing := range m.order {
+		if existing == hash {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *LoxMap) keys() []any {
+	keys := make([]any, len(m.order))
+	for i, hash := range m.order {
+		keys[i] = m.entries[hash].key
+	}
+	return keys
+}
+
+func (m *LoxMap) String() string {
+	return m.inspect(make(map[any]bool))
+}
+
+func (m *LoxMap) inspect(seen map[any]bool) string {
+	if seen[m] {
+		return "{...}"
+	}
+	seen[m] = true
+	defer delete(seen, m)
+
+	parts := make([]string, len(m.order))
+	for i, hash := range m.order {
+		entry := m.entries[hash]
+		parts[i] = fmt.Sprintf("%s: %s", inspectNested(entry.key, seen), inspectNested(entry.value, seen))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	runCases(t, []loxCase{
+		{"literal keeps order", `print {"b": 1, "a": 2, 3: nil};`, "{\"b\": 1, \"a\": 2, 3: nil}\n"},
+		{"empty", `print {};`, "{}\n"},
+		{"missing key", `var m = {"a": 1}; print m["b"];`, "nil\n"},
+		{"whole float key", `var m = {1: "one"}; print m[1.0];`, "one\n"},
+		{"boolean and nil keys", `var m = {true: "t", nil: "n"}; print m[true] + m[nil];`, "tn\n"},
+		{"duplicate key keeps the first position", `print {"a": 1, "b": 2, "a": 3};`,
+			"{\"a\": 3, \"b\": 2}\n"},
+		{"set and keys", `var m = {}; m["x"] = 1; m["y"] = 2; print keys(m);`, "[\"x\", \"y\"]\n"},
+		{"has and delete", `var m = {"a": 1}; delete(m, "a"); print has(m, "a"); print len(m);`,
+			"false\n0\n"},
+		{"delete a missing key", `print delete({}, "a");`, "false\n"},
+		{"iterate over keys", `var m = {"a": 1, "b": 2}; var ks = keys(m);
+for (var i = 0; i < len(ks); i = i + 1) print m[ks[i]];`,
+			"1\n2\n"},
+		{"map literal at statement start", `{"a": 1}["a"]; print "ok";`, "ok\n"},
+		{"identifier key at statement start", `var k = "key"; {k: 1}; print "ok";`, "ok\n"},
+		{"grouped key at statement start", `var a = "k"; {(a): 1}; print "ok";`, "ok\n"},
+		{"negative key at statement start", `{-1: 2}; print "ok";`, "ok\n"},
+		{"any key expression at statement start", `fun show(v) { print v; return v; } {[show("key")][0]: show(1), "b": 2};`,
+			"key\n1\n"},
+		{"conditional key at statement start", `{true ? "a" : "b": 1}; print "ok";`, "ok\n"},
+		{"conditional in a block", `{ print true ? "yes" : "no"; }`, "yes\n"},
+		{"map literal inside a block", `{ var m = {"a": 1}; print m["a"]; }`, "1\n"},
+		{"block at statement start", `{ print "block"; }`, "block\n"},
+		{"empty braces at statement start are a block", `{} print "ok";`, "ok\n"},
+		{"labelled loop at the start of a block", `{ outer: while (true) break outer; } print "ok";`,
+			"ok\n"},
+		{"contains itself", `var m = {"a": 1}; m["self"] = m; print m;`, "{\"a\": 1, \"self\": {...}}\n"},
+		{"cycle through a list", `var m = {}; m["l"] = [m]; print m; print m["l"];`, "{\"l\": [{...}]}\n[{\"l\": [...]}]\n"},
+		{"same map twice", `var m = {"a": 1}; print [m, m];`, "[{\"a\": 1}, {\"a\": 1}]\n"},
+		{"unhashable key", `var m = {}; m[[1]] = 1;`,
+			"error: Map keys must be nil, booleans, numbers or strings, got [1].\n  --> test.lox:1:14\n  |\n1 | var m = {}; m[[1]] = 1;\n  |              ^\n"},
+		{"unhashable key in a literal", `print {[1]: 1};`,
+			"error: Map keys must be nil, booleans, numbers or strings, got [1].\n  --> test.lox:1:7\n  |\n1 | print {[1]: 1};\n  |       ^\n"},
+		{"unhashable lookup", `var m = {}; print m[{}];`,
+			"error: Map keys must be nil, booleans, numbers or strings, got {}.\n  --> test.lox:1:20\n  |\n1 | var m = {}; print m[{}];\n  |                    ^\n"},
+		{"missing colon", `var m = {"a" 1};`,
+			"error: at '1': Expected ':' after map key.\n  --> test.lox:1:14\n  |\n1 | var m = {\"a\" 1};\n  |              ^\n"},
+		{"keys of a non-map", `keys([1]);`,
+			"error: Can only list the keys of a map.\n  --> test.lox:1:9\n  |\n1 | keys([1]);\n  |         ^\n  = in call to <native fn keys> at test.lox:1:9\n"},
+	})
+}
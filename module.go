@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoxModule is the runtime value of an imported module. Only the names the
+// module exported can be read from it, and they are read live from its
+// globals.
+type LoxModule struct {
+	name    string
+	globals *Environment
+	exports map[string]bool
+}
+
+func (m *LoxModule) get(name string) (any, bool) {
+	if !m.exports[name] {
+		return nil, false
+	}
+	value, err := m.globals.get(name)
+	return value, err == nil
+}
+
+// importBinding is what a name brought in with import { name } from "..."
+// holds. Reading the name reads the module's variable, so later changes made
+// by the module show through, just as they do through the module itself.
+type importBinding struct {
+	module *LoxModule
+	name   string
+}
+
+// readBinding gives the value of a variable holding value, following it
+// into the module it was imported from if need be.
+func readBinding(name string, value any) (any, error) {
+	binding, ok := value.(*importBinding)
+	if !ok {
+		return value, nil
+	}
+	if value, ok := binding.module.get(binding.name); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("Undefined variable : %s", name)
+}
+
+func (m *LoxModule) String() string {
+	return fmt.Sprintf("<module %s>", m.name)
+}
+
+// ModuleLoader runs each module the first time it is imported and hands out
+// the same module on every later import. Modules are keyed by absolute path.
+type ModuleLoader struct {
+	modules map[string]*LoxModule
+	// Files currently being run, outermost first, used to spot cycles.
+	loading []string
+}
+
+func NewModuleLoader() *ModuleLoader {
+	return &ModuleLoader{modules: make(map[string]*LoxModule)}
+}
+
+// enter and leave bracket running the file at path, so that an import of a
+// file that is still running is reported as a cycle.
+func (l *ModuleLoader) enter(path string) {
+	l.loading = append(l.loading, path)
+}
+
+func (l *ModuleLoader) leave() {
+	l.loading = l.loading[:len(l.loading)-1]
+}
+
+// load returns the module at the absolute path, running it in a fresh
+// interpreter with its own globals if it hasn't been loaded before. Errors
+// found inside the module are reported against its own source; the error
+// returned only says that the import failed.
+func (l *ModuleLoader) load(path string) (*LoxModule, error) {
+	if module, ok := l.modules[path]; ok {
+		return module, nil
+	}
+
+	for index, loading := range l.loading {
+		if loading == path {
+			var chain []string
+			for _, file := range append(l.loading[index:], path) {
+				chain = append(chain, displayPath(file))
+			}
+			return nil, fmt.Errorf("Import cycle: %s.", strings.Join(chain, " -> "))
+		}
+	}
+
+	name := displayPath(path)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read module '%s': %v.", name, errors.Unwrap(err))
+	}
+
+	diagnostics := NewDiagnostics(name, string(source))
+	stmts, ok := compile(diagnostics, string(source))
+	if !ok {
+		return nil, fmt.Errorf("Module '%s' has errors.", name)
+	}
+
+	l.enter(path)
+	defer l.leave()
+
+	interpreter := NewInterpreter(path, nil, nil, l)
+	_, err = interpreter.interpert(stmts)
+	if err != nil {
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			diagnostics.reportRuntime(runtimeErr)
+		}
+		return nil, fmt.Errorf("Module '%s' failed to run.", name)
+	}
+
+	module := &LoxModule{name: name, globals: interpreter.globals, exports: interpreter.exports}
+	l.modules[path] = module
+	return module, nil
+}
+
+// displayPath shortens path to be relative to the working directory when it
+// is inside it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	relative, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return path
+	}
+	return relative
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModules(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "import as",
+			files: map[string]string{
+				"main.lox": `import "math.lox" as math; print math.square(3);`,
+				"math.lox": `export fun square(x) { return x * x; }`,
+			},
+			want: "9\n",
+		},
+		{
+			name: "selective import",
+			files: map[string]string{
+				"main.lox": `import { pi, half } from "math.lox"; print half(pi);`,
+				"math.lox": `export var pi = 3.0; export fun half(x) { return x / 2; }`,
+			},
+			want: "1.5\n",
+		},
+		{
+			name: "imports are live",
+			files: map[string]string{
+				"main.lox":    `import { count, bump } from "counter.lox"; import "counter.lox" as c; bump(); print count; print c.count;`,
+				"counter.lox": `export var count = 0; export fun bump() { count = count + 1; }`,
+			},
+			want: "1\n1\n",
+		},
+		{
+			name: "imported names can't be assigned",
+			files: map[string]string{
+				"main.lox": `import { x } from "lib.lox"; x = 2;`,
+				"lib.lox":  `export var x = 1;`,
+			},
+			want: "error: Can't assign to imported name 'x'.\n  --> main.lox:1:30\n  |\n1 | import { x } from \"lib.lox\"; x = 2;\n  |                              ^\n",
+		},
+		{
+			name: "functions keep their module's globals",
+			files: map[string]string{
+				"main.lox": `import { who } from "lib.lox"; var name = "main"; print who();`,
+				"lib.lox":  `var name = "lib"; export fun who() { return name; }`,
+			},
+			want: "lib\n",
+		},
+		{
+			name: "methods keep their module's globals",
+			files: map[string]string{
+				"main.lox": `import { Greeter } from "lib.lox"; var greeting = "main"; print Greeter().greet();`,
+				"lib.lox":  `var greeting = "lib"; export class Greeter { greet() { return greeting; } }`,
+			},
+			want: "lib\n",
+		},
+		{
+			name: "assignments go to the function's module",
+			files: map[string]string{
+				"main.lox": `import "lib.lox" as lib; var total = 0; lib.add(5); print total; print lib.total;`,
+				"lib.lox":  `export var total = 0; export fun add(n) { total = total + n; }`,
+			},
+			want: "0\n5\n",
+		},
+		{
+			name: "modules run once",
+			files: map[string]string{
+				"main.lox": `import "a.lox"; import "b.lox";`,
+				"a.lox":    `import "b.lox";`,
+				"b.lox":    `print "b runs";`,
+			},
+			want: "b runs\n",
+		},
+		{
+			name: "unexported name",
+			files: map[string]string{
+				"main.lox": `import { secret } from "lib.lox";`,
+				"lib.lox":  `var secret = 1;`,
+			},
+			want: "error: Module 'lib.lox' has no export named 'secret'.\n  --> main.lox:1:10\n  |\n1 | import { secret } from \"lib.lox\";\n  |          ^~~~~~\n",
+		},
+		{
+			name: "paths are relative to the importing file",
+			files: map[string]string{
+				"main.lox":        `import "lib/strings.lox" as s; print s.shout("hi");`,
+				"lib/strings.lox": `import { bang } from "util.lox"; export fun shout(x) { return x + bang; }`,
+				"lib/util.lox":    `export var bang = "!";`,
+			},
+			want: "hi!\n",
+		},
+		{
+			name: "module members can't be assigned",
+			files: map[string]string{
+				"main.lox": `import "lib.lox" as lib; lib.x = 2;`,
+				"lib.lox":  `export var x = 1;`,
+			},
+			want: "error: Only instances have fields.\n  --> main.lox:1:30\n  |\n1 | import \"lib.lox\" as lib; lib.x = 2;\n  |                              ^\n",
+		},
+		{
+			name: "unexported member",
+			files: map[string]string{
+				"main.lox": `import "lib.lox" as lib; print lib.secret;`,
+				"lib.lox":  `var secret = 1;`,
+			},
+			want: "error: Module 'lib.lox' has no export named 'secret'.\n  --> main.lox:1:36\n  |\n1 | import \"lib.lox\" as lib; print lib.secret;\n  |                                    ^~~~~~\n",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"main.lox": `import "nowhere.lox";`,
+			},
+			want: "error: Can't read module 'nowhere.lox': no such file or directory.\n  --> main.lox:1:8\n  |\n1 | import \"nowhere.lox\";\n  |        ^~~~~~~~~~~~~\n",
+		},
+		{
+			name: "error inside a module",
+			files: map[string]string{
+				"main.lox": `import "lib.lox";`,
+				"lib.lox":  `print 1 / 0;`,
+			},
+			want: "error: Division by zero\n  --> lib.lox:1:9\n  |\n1 | print 1 / 0;\n  |         ^\n" +
+				"error: Module 'lib.lox' failed to run.\n  --> main.lox:1:8\n  |\n1 | import \"lib.lox\";\n  |        ^~~~~~~~~\n",
+		},
+		{
+			name: "syntax error inside a module",
+			files: map[string]string{
+				"main.lox": `import "lib.lox"; print "not reached";`,
+				"lib.lox":  `var = 1;`,
+			},
+			want: "error: at '=': Expect variable name.\n  --> lib.lox:1:5\n  |\n1 | var = 1;\n  |     ^\n" +
+				"error: Module 'lib.lox' has errors.\n  --> main.lox:1:8\n  |\n1 | import \"lib.lox\"; print \"not reached\";\n  |        ^~~~~~~~~\n",
+		},
+		{
+			name: "export inside a function",
+			files: map[string]string{
+				"main.lox": `fun f() { export var x = 1; }`,
+			},
+			want: "error: at 'export': Only top-level declarations can be exported.\n  --> main.lox:1:11\n  |\n1 | fun f() { export var x = 1; }\n  |           ^~~~~~\n",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.lox": `import "a.lox";`,
+				"a.lox":    `import "b.lox";`,
+				"b.lox":    `import "a.lox";`,
+			},
+			want: "error: Import cycle: a.lox -> b.lox -> a.lox.\n  --> b.lox:1:8\n  |\n1 | import \"a.lox\";\n  |        ^~~~~~~\n" +
+				"error: Module 'b.lox' failed to run.\n  --> a.lox:1:8\n  |\n1 | import \"b.lox\";\n  |        ^~~~~~~\n" +
+				"error: Module 'a.lox' failed to run.\n  --> main.lox:1:8\n  |\n1 | import \"a.lox\";\n  |        ^~~~~~~\n",
+		},
+		{
+			name: "cycle back to the main file",
+			files: map[string]string{
+				"main.lox": `import "a.lox";`,
+				"a.lox":    `import "main.lox";`,
+			},
+			want: "error: Import cycle: main.lox -> a.lox -> main.lox.\n  --> a.lox:1:8\n  |\n1 | import \"main.lox\";\n  |        ^~~~~~~~~~\n" +
+				"error: Module 'a.lox' failed to run.\n  --> main.lox:1:8\n  |\n1 | import \"a.lox\";\n  |        ^~~~~~~\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, source := range c.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			inDir(t, dir)
+
+			showTokens, showAst = false, false
+			got := capture(t, func() { runFile("main.lox") })
+			if got != c.want {
+				t.Errorf("got output\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+// inDir makes dir the working directory until the test ends, so that module
+// paths are shown relative to it.
+func inDir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
@@ -0,0 +1,113 @@
+package main
+
+import "fmt"
+
+// NativeFunction is a function built into the interpreter and implemented in
+// Go rather than in lox.
+type NativeFunction struct {
+	name     string
+	params   int
+	function func(interpreter *Interpreter, arguments []any) (any, error)
+}
+
+func (n *NativeFunction) arity() int {
+	return n.params
+}
+
+func (n *NativeFunction) call(interpreter *Interpreter, arguments []any) (any, error) {
+	return n.function(interpreter, arguments)
+}
+
+func (n *NativeFunction) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+func getNatives() []*NativeFunction {
+	return []*NativeFunction{
+		{name: "len", params: 1, function: nativeLen},
+		{name: "append", params: 2, function: nativeAppend},
+		{name: "pop", params: 1, function: nativePop},
+		{name: "keys", params: 1, function: nativeKeys},
+		{name: "has", params: 2, function: nativeHas},
+		{name: "delete", params: 2, function: nativeDelete},
+	}
+}
+
+// defineNatives adds the built-in functions and the Error class to env,
+// leaving alone any name the program has already claimed for itself.
+func defineNatives(env *Environment) {
+	for _, native := range getNatives() {
+		if _, ok := env.values[native.name]; !ok {
+			env.define(native.name, native)
+		}
+	}
+	if _, ok := env.values[errorClass.name]; !ok {
+		env.define(errorClass.name, errorClass)
+	}
+}
+
+func nativeLen(interpreter *Interpreter, arguments []any) (any, error) {
+	switch value := arguments[0].(type) {
+	case *LoxList:
+		return int64(len(value.elements)), nil
+	case *LoxMap:
+		return int64(len(value.order)), nil
+	case string:
+		return int64(len([]rune(value))), nil
+	}
+
+	return nil, interpreter.callError("Can only take the length of lists, maps and strings.")
+}
+
+func nativeAppend(interpreter *Interpreter, arguments []any) (any, error) {
+	list, ok := arguments[0].(*LoxList)
+	if !ok {
+		return nil, interpreter.callError("Can only append to a list.")
+	}
+
+	list.elements = append(list.elements, arguments[1])
+	return list, nil
+}
+
+func nativePop(interpreter *Interpreter, arguments []any) (any, error) {
+	list, ok := arguments[0].(*LoxList)
+	if !ok {
+		return nil, interpreter.callError("Can only pop from a list.")
+	}
+	if len(list.elements) == 0 {
+		return nil, interpreter.callError("Can't pop from an empty list.")
+	}
+
+	last := list.elements[len(list.elements)-1]
+	list.elements = list.elements[:len(list.elements)-1]
+	return last, nil
+}
+
+func nativeKeys(interpreter *Interpreter, arguments []any) (any, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, interpreter.callError("Can only list the keys of a map.")
+	}
+
+	return NewLoxList(m.keys()), nil
+}
+
+func nativeHas(interpreter *Interpreter, arguments []any) (any, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, interpreter.callError("Can only look for keys in a map.")
+	}
+
+	_, found := m.get(arguments[1])
+	return found, nil
+}
+
+// nativeDelete removes a key from a map and reports whether it was there.
+func nativeDelete(interpreter *Interpreter, arguments []any) (any, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, interpreter.callError("Can only delete keys from a map.")
+	}
+
+	return m.delete(arguments[1]), nil
+}
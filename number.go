@@ -0,0 +1,219 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Numbers are int64 when written as integer literals and float64 otherwise.
+// Arithmetic on two integers stays an integer, except for "/" which always
+// divides exactly; as soon as a float is involved the integer is promoted.
+// Integers that don't fit in an int64 are held as *big.Int, and go back to
+// int64 as soon as they fit again, so a *big.Int is never a small number.
+
+// toFloat converts any number to a float64.
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(value).Float64()
+		return f, true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// toBigInt converts an integer of either representation to a *big.Int.
+func toBigInt(value any) (*big.Int, bool) {
+	switch value := value.(type) {
+	case int64:
+		return big.NewInt(value), true
+	case *big.Int:
+		return value, true
+	}
+	return nil, false
+}
+
+// normalizeInt returns value as an int64 if it fits in one.
+func normalizeInt(value *big.Int) any {
+	if value.IsInt64() {
+		return value.Int64()
+	}
+	return value
+}
+
+// negateNumber gives -value for any number. Negating the smallest int64
+// overflows into a *big.Int.
+func negateNumber(value any) (any, bool) {
+	switch value := value.(type) {
+	case int64:
+		if value == math.MinInt64 {
+			return new(big.Int).Neg(big.NewInt(value)), true
+		}
+		return -value, true
+	case *big.Int:
+		return normalizeInt(new(big.Int).Neg(value)), true
+	case float64:
+		return -value, true
+	}
+	return nil, false
+}
+
+// bigFloorDivMod divides rounding towards negative infinity and returns the
+// matching remainder, like floorDiv and floorMod.
+func bigFloorDivMod(left *big.Int, right *big.Int) (*big.Int, *big.Int) {
+	quotient, remainder := new(big.Int).QuoRem(left, right, new(big.Int))
+	if remainder.Sign() != 0 && remainder.Sign() != right.Sign() {
+		quotient.Sub(quotient, big.NewInt(1))
+		remainder.Add(remainder, right)
+	}
+	return quotient, remainder
+}
+
+// exactNumber converts a finite number to a big.Float without rounding.
+func exactNumber(value any) (*big.Float, bool) {
+	switch value := value.(type) {
+	case int64:
+		return new(big.Float).SetInt64(value), true
+	case *big.Int:
+		return new(big.Float).SetInt(value), true
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, false
+		}
+		return new(big.Float).SetFloat64(value), true
+	}
+	return nil, false
+}
+
+// compareExact orders two numbers, at least one of them a *big.Int, without
+// rounding either to a float. It reports false when they can't be ordered
+// because the other one is NaN.
+func compareExact(left any, right any) (int, bool) {
+	leftExact, okLeft := exactNumber(left)
+	rightExact, okRight := exactNumber(right)
+	if okLeft && okRight {
+		return leftExact.Cmp(rightExact), true
+	}
+
+	// Only infinities and NaN have no exact value. An infinity lies beyond
+	// every integer however big.
+	leftNum, _ := toFloat(left)
+	rightNum, _ := toFloat(right)
+	if math.IsNaN(leftNum) || math.IsNaN(rightNum) {
+		return 0, false
+	}
+	if okLeft {
+		leftNum = 0
+	}
+	if okRight {
+		rightNum = 0
+	}
+	switch {
+	case leftNum < rightNum:
+		return -1, true
+	case leftNum > rightNum:
+		return 1, true
+	}
+	return 0, true
+}
+
+// isEqual compares two values for lox's == operator. Numbers compare by
+// value whatever their type, so 1 == 1.0.
+func isEqual(left any, right any) bool {
+	leftInt, okLeft := left.(int64)
+	rightInt, okRight := right.(int64)
+	if okLeft && okRight {
+		return leftInt == rightInt
+	}
+
+	// Big integers are compared exactly, even against a float.
+	_, okLeftBig := left.(*big.Int)
+	_, okRightBig := right.(*big.Int)
+	if okLeftBig || okRightBig {
+		leftExact, okLeft := exactNumber(left)
+		rightExact, okRight := exactNumber(right)
+		return okLeft && okRight && leftExact.Cmp(rightExact) == 0
+	}
+
+	leftNum, okLeft := toFloat(left)
+	rightNum, okRight := toFloat(right)
+	if okLeft || okRight {
+		return okLeft && okRight && leftNum == rightNum
+	}
+
+	// Functions are structs holding slices, which Go can't compare with ==.
+	// Two of them are the same function when they share a declaration and
+	// a closure.
+	if leftFn, ok := left.(LoxFunction); ok {
+		rightFn, ok := right.(LoxFunction)
+		return ok && leftFn.closure == rightFn.closure && leftFn.declaration.name == rightFn.declaration.name
+	}
+	if _, ok := right.(LoxFunction); ok {
+		return false
+	}
+
+	return left == right
+}
+
+// formatFloat prints a float so that it can't be mistaken for an integer:
+// whole floats keep a trailing ".0".
+func formatFloat(value float64) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	if value == math.Trunc(value) && math.Abs(value) < 1e21 {
+		return strconv.FormatFloat(value, 'f', 1, 64)
+	}
+
+	text := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	return text
+}
+
+// floorDiv divides rounding towards negative infinity, like "~/" on floats.
+func floorDiv(left int64, right int64) int64 {
+	quotient := left / right
+	if (left%right != 0) && ((left < 0) != (right < 0)) {
+		quotient--
+	}
+	return quotient
+}
+
+// floorMod is the remainder matching floorDiv: it takes the sign of right.
+func floorMod(left int64, right int64) int64 {
+	remainder := left % right
+	if remainder != 0 && ((remainder < 0) != (right < 0)) {
+		remainder += right
+	}
+	return remainder
+}
+
+// addInt64, subInt64 and mulInt64 report false when the result doesn't fit
+// in an int64.
+func addInt64(left int64, right int64) (int64, bool) {
+	sum := left + right
+	return sum, (sum > left) == (right > 0)
+}
+
+func subInt64(left int64, right int64) (int64, bool) {
+	difference := left - right
+	return difference, (difference < left) == (right > 0)
+}
+
+func mulInt64(left int64, right int64) (int64, bool) {
+	if left == 0 || right == 0 {
+		return 0, true
+	}
+	product := left * right
+	if product/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
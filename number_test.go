@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestArithmeticOperators(t *testing.T) {
+	runCases(t, []loxCase{
+		{"modulo", `print 7 % 3;`, "1\n"},
+		{"modulo takes the sign of the divisor", `print -7 % 3; print 7 % -3;`, "2\n-2\n"},
+		{"fractional modulo", `print 5.5 % 2;`, "1.5\n"},
+		{"power", `print 2 ** 10;`, "1024\n"},
+		{"power is right associative", `print 2 ** 3 ** 2;`, "512\n"},
+		{"power binds tighter than unary minus on its left", `print -2 ** 2;`, "-4\n"},
+		{"negative exponent", `print 2 ** -1;`, "0.5\n"},
+		{"power binds tighter than multiplication", `print 3 * 2 ** 2;`, "12\n"},
+		{"floor division", `print 7 ~/ 2; print -7 ~/ 2;`, "3\n-4\n"},
+		{"floor division shares a level with multiplication", `print 2 * 7 ~/ 2;`, "7\n"},
+		{"double slash is still a comment", `print 1; // print 2;`, "1\n"},
+		{"modulo by zero", `print 1 % 0;`,
+			"error: Modulo by zero\n  --> test.lox:1:9\n  |\n1 | print 1 % 0;\n  |         ^\n"},
+		{"floor division by zero", `print 1 ~/ 0;`,
+			"error: Division by zero\n  --> test.lox:1:9\n  |\n1 | print 1 ~/ 0;\n  |         ^~\n"},
+		{"operands must be numbers", `print "a" ** 2;`,
+			"error: Unexpected values for operator: **\n  --> test.lox:1:11\n  |\n1 | print \"a\" ** 2;\n  |           ^~\n"},
+	})
+}
+
+func TestIntegers(t *testing.T) {
+	runCases(t, []loxCase{
+		{"division of integers", `print 7 / 2; print 7 ~/ 2; print 6 / 3;`, "3.5\n3\n2.0\n"},
+		{"floats print with a fraction", `print 1.0; print 2.5 * 2;`, "1.0\n5.0\n"},
+		{"mixed arithmetic promotes to float", `print 1 + 0.5; print 2 * 1.0;`, "1.5\n2.0\n"},
+		{"integer equals float", `print 1 == 1.0; print 1 != 1.5;`, "true\ntrue\n"},
+		{"integer and float map keys", `var m = {1: "one"}; print m[1.0]; print len({1: "a", 1.0: "b"});`,
+			"one\n1\n"},
+		{"list index must be whole", `print [1, 2][1.0];`, "2\n"},
+		{"integer power", `print 3 ** 3; print 2 ** 0.5 > 1.41;`, "27\ntrue\n"},
+		{"integer modulo", `print -7 % 3; print 7.5 % 2;`, "2\n1.5\n"},
+		{"hex literals are integers", `print 0xFF; print 1e2;`, "255\n100.0\n"},
+		{"and", `print 12 & 10;`, "8\n"},
+		{"or", `print 12 | 3;`, "15\n"},
+		{"xor", `print 12 ^ 10;`, "6\n"},
+		{"not", `print ~5;`, "-6\n"},
+		{"shifts", `print 1 << 4; print -16 >> 2;`, "16\n-4\n"},
+		{"bitwise precedence", `print 1 | 2 & 3 ^ 4; print 1 + 1 << 2;`, "7\n8\n"},
+		{"integer compound assignment", `var x = 6; x += 1; print x;`, "7\n"},
+		{"bitwise needs integers", `print 1.5 & 1;`,
+			"error: Operands of '&' must be integers.\n  --> test.lox:1:11\n  |\n1 | print 1.5 & 1;\n  |           ^\n"},
+		{"not needs an integer", `print ~1.0;`,
+			"error: Operand of '~' must be an integer.\n  --> test.lox:1:7\n  |\n1 | print ~1.0;\n  |       ^\n"},
+		{"negative shift", `print 1 << -1;`,
+			"error: Negative shift count -1\n  --> test.lox:1:9\n  |\n1 | print 1 << -1;\n  |         ^~\n"},
+		{"integer division by zero", `print 1 / 0;`,
+			"error: Division by zero\n  --> test.lox:1:9\n  |\n1 | print 1 / 0;\n  |         ^\n"},
+	})
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	runCases(t, []loxCase{
+		{"add stays int64", "print 9223372036854775806 + 1;", "9223372036854775807\n"},
+		{"add overflows", "print 9223372036854775807 + 1;", "9223372036854775808\n"},
+		{"subtract overflows", "print -9223372036854775807 - 2;", "-9223372036854775809\n"},
+		{"multiply overflows", "print 4294967296 * 4294967296;", "18446744073709551616\n"},
+		{"negate smallest int64", "var n = -9223372036854775807 - 1; print -n;", "9223372036854775808\n"},
+		{"power", "print 2 ** 100;", "1267650600228229401496703205376\n"},
+		{"shift left", "print 1 << 70;", "1180591620717411303424\n"},
+		{"big literal", "print 123456789012345678901234567890;", "123456789012345678901234567890\n"},
+		{"back to int64", "var big = 9223372036854775807 + 1; print big - 1 == 9223372036854775807;", "true\n"},
+		{"floor division", "print (2 ** 70) ~/ -3;", "-393530540239137101142\n"},
+		{"modulo", "print (2 ** 70) % -3;", "-2\n"},
+		{"division is exact", "print (2 ** 70) / 2 ** 69;", "2.0\n"},
+		{"big with float", "print 2 ** 64 + 0.5;", "18446744073709551616.0\n"},
+		{"big equals float", "print 2 ** 64 == 18446744073709551616.0;", "true\n"},
+		{"big as map key", `var m = {}; m[2 ** 64] = "a"; print m[2 ** 64];`, "a\n"},
+		{"compare big integers", "print 2 ** 70 > 2 ** 69; print 2 ** 64 < 1; print -(2 ** 64) < 1;",
+			"true\nfalse\ntrue\n"},
+		{"big ordered against a float exactly", `var b = 2 ** 64 + 1; var f = 18446744073709551616.0;
+print b == f; print b > f; print b >= f; print b < f; print b <= f; print f < b;`,
+			"false\ntrue\ntrue\nfalse\nfalse\ntrue\n"},
+		{"big ordered against an equal float", `var f = 18446744073709551616.0; print 2 ** 64 > f; print 2 ** 64 >= f; print f <= 2 ** 64;`,
+			"false\ntrue\ntrue\n"},
+		{"big ordered against infinity", `var inf = 1e308 * 10; print 2 ** 2000 < inf; print 2 ** 2000 > inf; print -(2 ** 2000) > -inf;`,
+			"true\nfalse\ntrue\n"},
+		{"big ordered against NaN", `var inf = 1e308 * 10; var nan = inf - inf; print 2 ** 64 < nan; print 2 ** 64 >= nan;`,
+			"false\nfalse\n"},
+		{"big ordered against a string", `print 2 ** 64 < "a";`,
+			"error: Unexpected values for operator: <\n  --> test.lox:1:15\n  |\n1 | print 2 ** 64 < \"a\";\n  |               ^\n"},
+		{"big integers are equal by value", "print 2 ** 64 == 2 ** 63 * 2; print 2 ** 64 != 2 ** 64 + 1;",
+			"true\ntrue\n"},
+		{"bitwise on big integers", "print (2 ** 64 + 5) & 7; print (2 ** 64) >> 60;", "5\n16\n"},
+		{"big in a list", "print [2 ** 64];", "[18446744073709551616]\n"},
+		{"compound assignment overflows", "var x = 9223372036854775807; x += 1; x++; print x;",
+			"9223372036854775809\n"},
+		{"big division by zero", "print (2 ** 64) ~/ 0;",
+			"error: Division by zero\n  --> test.lox:1:17\n  |\n1 | print (2 ** 64) ~/ 0;\n  |                 ^~\n"},
+		{"big modulo by zero", "print (2 ** 64) % 0;",
+			"error: Modulo by zero\n  --> test.lox:1:17\n  |\n1 | print (2 ** 64) % 0;\n  |                 ^\n"},
+		{"big negative shift", "print (2 ** 64) << -1;",
+			"error: Negative shift count -1\n  --> test.lox:1:17\n  |\n1 | print (2 ** 64) << -1;\n  |                 ^~\n"},
+		{"int division by zero", "print 1 ~/ 0;",
+			"error: Division by zero\n  --> test.lox:1:9\n  |\n1 | print 1 ~/ 0;\n  |         ^~\n"},
+	})
+}
+
+func TestAddInt64(t *testing.T) {
+	cases := []struct {
+		left, right int64
+		want        int64
+		ok          bool
+	}{
+		{1, 2, 3, true},
+		{math.MaxInt64, 0, math.MaxInt64, true},
+		{math.MaxInt64, 1, 0, false},
+		{math.MinInt64, -1, 0, false},
+		{math.MinInt64, math.MaxInt64, -1, true},
+	}
+	for _, c := range cases {
+		got, ok := addInt64(c.left, c.right)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("addInt64(%d, %d) = %d, %t; want %d, %t", c.left, c.right, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestMulInt64(t *testing.T) {
+	cases := []struct {
+		left, right int64
+		want        int64
+		ok          bool
+	}{
+		{0, math.MinInt64, 0, true},
+		{-1, math.MaxInt64, -math.MaxInt64, true},
+		{-1, math.MinInt64, 0, false},
+		{math.MinInt64, -1, 0, false},
+		{1 << 32, 1 << 31, 0, false},
+		{1 << 31, 1 << 31, 1 << 62, true},
+	}
+	for _, c := range cases {
+		got, ok := mulInt64(c.left, c.right)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("mulInt64(%d, %d) = %d, %t; want %d, %t", c.left, c.right, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestNormalizeInt(t *testing.T) {
+	if got := normalizeInt(big.NewInt(42)); got != int64(42) {
+		t.Errorf("normalizeInt(42) = %#v; want int64(42)", got)
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 64)
+	if got, ok := normalizeInt(huge).(*big.Int); !ok || got.Cmp(huge) != 0 {
+		t.Errorf("normalizeInt(2**64) = %#v; want *big.Int", normalizeInt(huge))
+	}
+}
@@ -1,19 +1,34 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type Parser struct {
 	tokens        []Token
 	current       int
 	errorReporter func(*Token, int, int, string)
+	// Warnings are reported but don't stop the program from running.
+	warningReporter func(*Token, string)
+	// Labels of the loops enclosing the statement being parsed, innermost
+	// last. Unlabelled loops are recorded as "".
+	loopLabels []string
+	// Number of function bodies enclosing the statement being parsed.
+	functionDepth int
+	// Number of blocks enclosing the statement being parsed. Error recovery
+	// only treats a '}' as the end of a block when there is one to end.
+	blockDepth int
+	// Set while parsing call arguments, where a comma separates arguments
+	// instead of acting as the comma operator.
+	noComma bool
+	// Every syntax error found so far. The parser resynchronizes after each
+	// one so that a single pass reports them all.
+	errors []error
 }
 
-func reportErrorParse(token *Token, line int, where int, message string) {
-	fmt.Printf("[line %d, col %d] Error at %s, %s\n", line, where, token.lexeme, message)
-}
-
-func NewParser(tokens []Token, reportError func(*Token, int, int, string)) Parser {
-	return Parser{tokens: tokens, current: 0, errorReporter: reportError}
+func NewParser(tokens []Token, reportError func(*Token, int, int, string), reportWarning func(*Token, string)) Parser {
+	return Parser{tokens: tokens, current: 0, errorReporter: reportError, warningReporter: reportWarning}
 }
 
 func (p *Parser) parse() ([]Statement, error) {
@@ -21,15 +36,44 @@ func (p *Parser) parse() ([]Statement, error) {
 	for !p.isAtEnd() {
 		stmt, err := p.declaration()
 		if err != nil {
-			return nil, err
+			continue
 		}
 		statements = append(statements, stmt)
 	}
 
+	if len(p.errors) > 0 {
+		return nil, errors.Join(p.errors...)
+	}
 	return statements, nil
 }
 
+// declaration parses the next declaration. When it fails the error is
+// recorded and the parser skips ahead to the start of the next statement, so
+// callers can simply drop the declaration and carry on.
 func (p *Parser) declaration() (Statement, error) {
+	start := p.current
+	stmt, err := p.declarationOrError()
+	if err != nil {
+		p.errors = append(p.errors, err)
+		// Make sure the offending token is skipped when it could not even
+		// start a declaration, otherwise the parser would never move on.
+		if p.current == start {
+			p.advance()
+		}
+		p.synchronize()
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) declarationOrError() (Statement, error) {
+	if p.match(TOKEN_CLASS) {
+		return p.classDeclaration()
+	}
+	if p.match(TOKEN_FUN) {
+		return p.function("function")
+	}
 	if p.match(TOKEN_VAR) {
 		if stmt, err := p.variableDeclaration(); err != nil {
 			return nil, err
@@ -37,284 +81,1494 @@ func (p *Parser) declaration() (Statement, error) {
 			return stmt, nil
 		}
 	}
+	if p.match(TOKEN_CONST) {
+		return p.constDeclaration()
+	}
+	if p.match(TOKEN_IMPORT) {
+		return p.importDeclaration()
+	}
+	if p.match(TOKEN_EXPORT) {
+		return p.exportDeclaration()
+	}
 
 	return p.statement()
 }
 
+// importDeclaration parses the two forms of import. "as" and "from" are only
+// special here, so they remain usable as names elsewhere.
+func (p *Parser) importDeclaration() (Statement, error) {
+	keyword := p.previous()
+	stmt := ImportStatement{keyword: *keyword}
+
+	if p.match(TOKEN_LEFT_BRACE) {
+		for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
+			name, err := p.consume(TOKEN_IDENTIFIER, "Expected a name to import.")
+			if err != nil {
+				return nil, err
+			}
+			stmt.names = append(stmt.names, *name)
+			if !p.match(TOKEN_COMMA) {
+				break
+			}
+		}
+		_, err := p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after imported names.")
+		if err != nil {
+			return nil, err
+		}
+		if len(stmt.names) == 0 {
+			return nil, p.error(p.previous(), "Expected at least one name to import.")
+		}
+		_, err = p.consumeWord("from", "Expected 'from' after imported names.")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	path, err := p.consume(TOKEN_STRING, "Expected the module's path as a string.")
+	if err != nil {
+		return nil, err
+	}
+	stmt.path = *path
+
+	if stmt.names == nil && p.check(TOKEN_IDENTIFIER) {
+		_, err = p.consumeWord("as", "Expected 'as' after the module's path.")
+		if err != nil {
+			return nil, err
+		}
+		stmt.alias, err = p.consume(TOKEN_IDENTIFIER, "Expected a name for the module after 'as'.")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after import.")
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *Parser) exportDeclaration() (Statement, error) {
+	keyword := p.previous()
+
+	var declaration Statement
+	var err error
+	switch {
+	case p.match(TOKEN_CLASS):
+		declaration, err = p.classDeclaration()
+	case p.match(TOKEN_FUN):
+		declaration, err = p.function("function")
+	case p.match(TOKEN_VAR):
+		declaration, err = p.variableDeclaration()
+	case p.match(TOKEN_CONST):
+		declaration, err = p.constDeclaration()
+	default:
+		tok := p.peek()
+		return nil, p.error(&tok, "Expected a class, function, variable or constant declaration after 'export'.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var name Token
+	switch declaration := declaration.(type) {
+	case ClassStatement:
+		name = declaration.name
+	case FunctionStatement:
+		name = declaration.name
+	case VarDeclarationStatement:
+		name = declaration.name
+	}
+	return ExportStatement{keyword: *keyword, name: name, declaration: declaration}, nil
+}
+
 func (p *Parser) statement() (Statement, error) {
+	if p.match(TOKEN_IF) {
+		return p.ifStatement()
+	}
+	if p.check(TOKEN_IDENTIFIER) && p.checkNext(TOKEN_COLON) {
+		return p.labeledStatement()
+	}
+	if p.match(TOKEN_WHILE) {
+		return p.whileStatement(nil)
+	}
+	if p.match(TOKEN_FOR) {
+		return p.forStatement(nil)
+	}
+	if p.match(TOKEN_BREAK) {
+		return p.breakStatement()
+	}
+	if p.match(TOKEN_CONTINUE) {
+		return p.continueStatement()
+	}
+	if p.match(TOKEN_RETURN) {
+		return p.returnStatement()
+	}
+	if p.match(TOKEN_THROW) {
+		return p.throwStatement()
+	}
+	if p.match(TOKEN_TRY) {
+		return p.tryStatement()
+	}
 	if p.match(TOKEN_PRINT) {
 		return p.printStatement()
 	}
-	if p.match(TOKEN_LEFT_BRACE) {
+	if p.check(TOKEN_LEFT_BRACE) && !p.startsMapLiteral() {
+		p.advance()
 		return p.blockStatement()
 	}
 
 	return p.expressionStatement()
 }
 
+// startsMapLiteral tells a map literal at the start of a statement apart from
+// a block. Whatever expression a map's first key is, it is followed by a ':'
+// outside any brackets, where the first statement of a block reaches a ';' or
+// the closing '}' first. The ':' of a conditional or of a loop label doesn't
+// count. An empty pair of braces is always a block.
+func (p *Parser) startsMapLiteral() bool {
+	depth := 0
+	conditionals := 0
+	for index := p.current + 1; index < len(p.tokens); index++ {
+		switch p.tokens[index].tokenType {
+		case TOKEN_LEFT_PAREN, TOKEN_LEFT_BRACKET, TOKEN_LEFT_BRACE:
+			depth++
+		case TOKEN_RIGHT_PAREN, TOKEN_RIGHT_BRACKET, TOKEN_RIGHT_BRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case TOKEN_SEMICOLON:
+			if depth == 0 {
+				return false
+			}
+		case TOKEN_EOF:
+			return false
+		case TOKEN_QUESTION_MARK:
+			if depth == 0 {
+				conditionals++
+			}
+		case TOKEN_COLON:
+			if depth > 0 {
+				continue
+			}
+			if conditionals > 0 {
+				conditionals--
+				continue
+			}
+			if index+1 < len(p.tokens) {
+				next := p.tokens[index+1].tokenType
+				return next != TOKEN_WHILE && next != TOKEN_FOR
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *Parser) blockStatement() (Statement, error) {
+	brace := p.previous()
+	statements, err := p.blockBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return BlockStatement{brace: *brace, stmts: statements}, nil
+}
+
+func (p *Parser) blockBody() ([]Statement, error) {
 	var statements []Statement
 
-	for !p.check(TOKEN_RIGHT_BRACE) {
+	p.blockDepth++
+	for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
 		next, err := p.declaration()
 		if err != nil {
-			return nil, err
+			continue
 		}
 		statements = append(statements, next)
 	}
-	p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after block.")
+	p.blockDepth--
+
+	_, err := p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after block.")
+	if err != nil {
+		return nil, err
+	}
+
+	return statements, nil
+}
+
+func (p *Parser) classDeclaration() (Statement, error) {
+	name, err := p.consume(TOKEN_IDENTIFIER, "Expected class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *Variable
+	if p.match(TOKEN_LESS) {
+		superName, err := p.consume(TOKEN_IDENTIFIER, "Expected superclass name.")
+		if err != nil {
+			return nil, err
+		}
+		superclass = &Variable{name: *superName, depth: newDepth()}
+	}
+
+	_, err = p.consume(TOKEN_LEFT_BRACE, "Expected '{' before class body.")
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []FunctionStatement
+	for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
+		method, err := p.function("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method.(FunctionStatement))
+	}
+
+	_, err = p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after class body.")
+	if err != nil {
+		return nil, err
+	}
+
+	return ClassStatement{name: *name, superclass: superclass, methods: methods}, nil
+}
+
+func (p *Parser) function(kind string) (Statement, error) {
+	name, err := p.consume(TOKEN_IDENTIFIER, fmt.Sprintf("Expected %s name.", kind))
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_LEFT_PAREN, fmt.Sprintf("Expected '(' after %s name.", kind))
+	if err != nil {
+		return nil, err
+	}
+
+	var params []Token
+	if !p.check(TOKEN_RIGHT_PAREN) {
+		for {
+			param, err := p.consume(TOKEN_IDENTIFIER, "Expected parameter name.")
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, *param)
+			if !p.match(TOKEN_COMMA) {
+				break
+			}
+		}
+	}
+	_, err = p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_LEFT_BRACE, fmt.Sprintf("Expected '{' before %s body.", kind))
+	if err != nil {
+		return nil, err
+	}
+
+	// Loops outside the function can't be targeted from within its body.
+	enclosingLoops := p.loopLabels
+	p.loopLabels = nil
+	p.functionDepth++
+	body, err := p.blockBody()
+	p.functionDepth--
+	p.loopLabels = enclosingLoops
+	if err != nil {
+		return nil, err
+	}
+
+	return FunctionStatement{name: *name, params: params, body: body}, nil
+}
+
+func (p *Parser) returnStatement() (Statement, error) {
+	keyword := p.previous()
+	if p.functionDepth == 0 {
+		return nil, p.error(keyword, "Can't return from top-level code.")
+	}
+
+	var value Expr
+	var err error
+	if !p.check(TOKEN_SEMICOLON) {
+		value, err = p.assignment()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after return value.")
+	if err != nil {
+		return nil, err
+	}
+
+	return ReturnStatement{keyword: *keyword, value: value}, nil
+}
+
+func (p *Parser) throwStatement() (Statement, error) {
+	keyword := p.previous()
+	value, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after thrown value.")
+	if err != nil {
+		return nil, err
+	}
+
+	return ThrowStatement{keyword: *keyword, value: value}, nil
+}
+
+func (p *Parser) tryStatement() (Statement, error) {
+	keyword := p.previous()
+	body, err := p.clauseBody("try")
+	if err != nil {
+		return nil, err
+	}
+	stmt := TryStatement{keyword: *keyword, body: body}
+
+	if p.match(TOKEN_CATCH) {
+		_, err = p.consume(TOKEN_LEFT_PAREN, "Expected '(' after 'catch'.")
+		if err != nil {
+			return nil, err
+		}
+		stmt.catchName, err = p.consume(TOKEN_IDENTIFIER, "Expected a name for the caught value.")
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after the caught value's name.")
+		if err != nil {
+			return nil, err
+		}
+		catchBody, err := p.clauseBody("catch")
+		if err != nil {
+			return nil, err
+		}
+		stmt.catchBody = &catchBody
+	}
+
+	if p.match(TOKEN_FINALLY) {
+		finallyBody, err := p.clauseBody("finally")
+		if err != nil {
+			return nil, err
+		}
+		stmt.finallyBody = &finallyBody
+	}
+
+	if stmt.catchBody == nil && stmt.finallyBody == nil {
+		return nil, p.error(keyword, "Expected 'catch' or 'finally' after try block.")
+	}
+
+	return stmt, nil
+}
+
+// clauseBody parses the block that must follow the keyword of a try, catch
+// or finally clause.
+func (p *Parser) clauseBody(clause string) (BlockStatement, error) {
+	_, err := p.consume(TOKEN_LEFT_BRACE, fmt.Sprintf("Expected '{' to start the %s block.", clause))
+	if err != nil {
+		return BlockStatement{}, err
+	}
+	body, err := p.blockStatement()
+	if err != nil {
+		return BlockStatement{}, err
+	}
+	return body.(BlockStatement), nil
+}
+
+func (p *Parser) ifStatement() (Statement, error) {
+	_, err := p.consume(TOKEN_LEFT_PAREN, "Expected '(' after 'if'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after if condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	thenBranch, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	// An else always binds to the nearest if, so a dangling else is claimed
+	// by the innermost statement that is still being parsed.
+	var elseBranch Statement
+	if p.match(TOKEN_ELSE) {
+		elseBranch, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return IfStatement{condition: condition, thenBranch: thenBranch, elseBranch: elseBranch}, nil
+}
+
+func (p *Parser) labeledStatement() (Statement, error) {
+	label := p.advance()
+	p.advance() // the ':' after the label
+
+	for _, enclosing := range p.loopLabels {
+		if enclosing == label.lexeme {
+			return nil, p.error(label, fmt.Sprintf("Label '%s' is already used by an enclosing loop.", label.lexeme))
+		}
+	}
+
+	if p.match(TOKEN_WHILE) {
+		return p.whileStatement(label)
+	}
+	if p.match(TOKEN_FOR) {
+		return p.forStatement(label)
+	}
+
+	tok := p.peek()
+	return nil, p.error(&tok, "Expected a loop after label.")
+}
+
+func (p *Parser) loopBody(label *Token) (Statement, error) {
+	p.loopLabels = append(p.loopLabels, labelName(label))
+	body, err := p.statement()
+	p.loopLabels = p.loopLabels[:len(p.loopLabels)-1]
+
+	return body, err
+}
+
+func (p *Parser) breakStatement() (Statement, error) {
+	keyword := p.previous()
+	label, err := p.jumpLabel(keyword)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after 'break'.")
+	if err != nil {
+		return nil, err
+	}
+
+	return BreakStatement{keyword: *keyword, label: label}, nil
+}
+
+func (p *Parser) continueStatement() (Statement, error) {
+	keyword := p.previous()
+	label, err := p.jumpLabel(keyword)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after 'continue'.")
+	if err != nil {
+		return nil, err
+	}
+
+	return ContinueStatement{keyword: *keyword, label: label}, nil
+}
+
+// jumpLabel checks that a break or continue sits inside a loop and parses the
+// optional label naming which enclosing loop it applies to.
+func (p *Parser) jumpLabel(keyword *Token) (*Token, error) {
+	if len(p.loopLabels) == 0 {
+		return nil, p.error(keyword, fmt.Sprintf("Can't use '%s' outside of a loop.", keyword.lexeme))
+	}
+
+	if !p.match(TOKEN_IDENTIFIER) {
+		return nil, nil
+	}
+
+	label := p.previous()
+	for _, enclosing := range p.loopLabels {
+		if enclosing == label.lexeme {
+			return label, nil
+		}
+	}
+
+	return nil, p.error(label, fmt.Sprintf("No enclosing loop is labelled '%s'.", label.lexeme))
+}
+
+func (p *Parser) whileStatement(label *Token) (Statement, error) {
+	_, err := p.consume(TOKEN_LEFT_PAREN, "Expected '(' after 'while'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after while condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return WhileStatement{label: label, condition: condition, body: body}, nil
+}
+
+func (p *Parser) forStatement(label *Token) (Statement, error) {
+	_, err := p.consume(TOKEN_LEFT_PAREN, "Expected '(' after 'for'.")
+	if err != nil {
+		return nil, err
+	}
+
+	var initializer Statement
+	if p.match(TOKEN_SEMICOLON) {
+		initializer = nil
+	} else if p.match(TOKEN_VAR) {
+		initializer, err = p.variableDeclaration()
+	} else {
+		initializer, err = p.expressionStatement()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition Expr
+	if !p.check(TOKEN_SEMICOLON) {
+		condition, err = p.assignment()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after loop condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	var increment Expr
+	if !p.check(TOKEN_RIGHT_PAREN) {
+		increment, err = p.assignment()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after for clauses.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForStatement{label: label, initializer: initializer, condition: condition, increment: increment, body: body}, nil
+}
+
+func (p *Parser) printStatement() (Statement, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after expression.")
+	if err != nil {
+		return nil, err
+	}
+	return PrintStatement{expr}, nil
+}
+
+func (p *Parser) expressionStatement() (Statement, error) {
+	expr, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after expression.")
+	if err != nil {
+		return nil, err
+	}
+	return ExpressionStatement{expr}, nil
+}
+
+func (p *Parser) declarationStatement() (Statement, error) {
+	if p.match(TOKEN_VAR) {
+		return p.variableDeclaration()
+	}
+
+	return p.statement()
+}
+
+func (p *Parser) variableDeclaration() (Statement, error) {
+	name, err := p.consume(TOKEN_IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var expr Expr
+	if p.match(TOKEN_EQUAL) {
+		expr, err = p.assignment()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(TOKEN_SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+
+	return VarDeclarationStatement{name: *name, initializer: expr}, nil
+}
+
+func (p *Parser) constDeclaration() (Statement, error) {
+	name, err := p.consume(TOKEN_IDENTIFIER, "Expect constant name.")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(TOKEN_EQUAL, "Expect '=' after constant name, constants must be initialized.")
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(TOKEN_SEMICOLON, "Expect ';' after constant declaration.")
+	if err != nil {
+		return nil, err
+	}
+
+	return VarDeclarationStatement{name: *name, initializer: expr, constant: true}, nil
+}
+
+func (p *Parser) assignment() (Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(TOKEN_EQUAL) {
+		equals := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		if varExpr, ok := expr.(Variable); ok {
+			name := varExpr.name
+			return Assign{name: name, value: value, depth: newDepth()}, nil
+		}
+		if getExpr, ok := expr.(Get); ok && !getExpr.optional {
+			return Set{object: getExpr.object, name: getExpr.name, value: value}, nil
+		}
+		if indexExpr, ok := expr.(Index); ok && !indexExpr.optional {
+			return SetIndex{object: indexExpr.object, bracket: indexExpr.bracket, index: indexExpr.index, value: value}, nil
+		}
+
+		return nil, p.error(equals, "Invalid assignment target.")
+	}
+
+	if p.match(TOKEN_PLUS_EQUAL, TOKEN_MINUS_EQUAL, TOKEN_STAR_EQUAL, TOKEN_SLASH_EQUAL) {
+		operator := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAssignable(expr) {
+			return nil, p.error(operator, "Invalid assignment target.")
+		}
+		return CompoundAssign{target: expr, operator: *operator, value: value}, nil
+	}
+
+	return expr, nil
+}
+
+// isAssignable reports whether expr can be the target of a compound
+// assignment or an increment.
+func isAssignable(expr Expr) bool {
+	switch expr := expr.(type) {
+	case Variable:
+		return true
+	case Get:
+		return !expr.optional
+	case Index:
+		return !expr.optional
+	}
+	return false
+}
+
+func (p *Parser) expression() (Expr, error) {
+	return p.ternary()
+}
+
+func (p *Parser) ternary() (Expr, error) {
+	condition, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(TOKEN_QUESTION_MARK) {
+		left, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(TOKEN_COLON, "Expected ':' in Ternay expression")
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.ternary()
+		return Ternary{condition: condition, left: left, right: right}, nil
+	}
+
+	return condition, nil
+}
+
+func (p *Parser) block() (Expr, error) {
+	expr, err := p.coalesce()
+	if err != nil {
+		return nil, err
+	}
+	for !p.noComma && p.match(TOKEN_COMMA) {
+		operator := p.previous()
+		right, err := p.coalesce()
+		if err != nil {
+			return nil, err
+		}
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+// coalesce parses the right associative "??" operator, which gives its left
+// operand unless that is nil.
+func (p *Parser) coalesce() (Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(TOKEN_QUESTION_QUESTION) {
+		operator := p.previous()
+		right, err := p.coalesce()
+		if err != nil {
+			return nil, err
+		}
+		expr = Logical{left: expr, operator: *operator, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) or() (Expr, error) {
+	expr, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_OR) {
+		operator := p.previous()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		expr = Logical{left: expr, operator: *operator, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) and() (Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_AND) {
+		operator := p.previous()
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = Logical{left: expr, operator: *operator, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) equality() (Expr, error) {
+	expr, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_BANG_EQUAL, TOKEN_EQUAL_EQUAL) {
+		operator := p.previous()
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) comparison() (Expr, error) {
+	expr, err := p.bitOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_GREATER, TOKEN_GREATER_EQUAL, TOKEN_LESS, TOKEN_LESS_EQUAL) {
+		operator := p.previous()
+		right, err := p.bitOr()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+// The bitwise operators bind tighter than comparisons, as in Python, so that
+// x & mask == 0 tests the masked value.
+func (p *Parser) bitOr() (Expr, error) {
+	expr, err := p.bitXor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_PIPE) {
+		operator := p.previous()
+		right, err := p.bitXor()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitXor() (Expr, error) {
+	expr, err := p.bitAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_CARET) {
+		operator := p.previous()
+		right, err := p.bitAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitAnd() (Expr, error) {
+	expr, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_AMPERSAND) {
+		operator := p.previous()
+		right, err := p.shift()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) shift() (Expr, error) {
+	expr, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_LESS_LESS, TOKEN_GREATER_GREATER) {
+		operator := p.previous()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) term() (Expr, error) {
+	expr, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_MINUS, TOKEN_PLUS) {
+		operator := p.previous()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) factor() (Expr, error) {
+	expr, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(TOKEN_SLASH, TOKEN_STAR, TOKEN_PERCENT, TOKEN_TILDE_SLASH) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) unary() (Expr, error) {
+	if p.match(TOKEN_BANG, TOKEN_MINUS, TOKEN_TILDE) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Unary{operator: *operator, right: right}, nil
+	}
+
+	if p.match(TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS) {
+		operator := p.previous()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if !isAssignable(target) {
+			return nil, p.error(operator, fmt.Sprintf("Invalid operand for '%s'.", operator.lexeme))
+		}
+		return Increment{target: target, operator: *operator, prefix: true}, nil
+	}
+
+	return p.power()
+}
+
+// power binds tighter than a unary operator on its left, so -2 ** 2 is
+// -(2 ** 2), and its right operand is a unary so that it associates to the
+// right.
+func (p *Parser) power() (Expr, error) {
+	expr, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(TOKEN_STAR_STAR) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	}
 
-	return BlockStatement{stmts: statements}, nil
+	return expr, nil
 }
 
-func (p *Parser) printStatement() (Statement, error) {
-	expr, err := p.expression()
+func (p *Parser) postfix() (Expr, error) {
+	expr, err := p.call()
 	if err != nil {
 		return nil, err
 	}
-	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after expression.")
-	return PrintStatement{expr}, nil
 
+	if p.match(TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS) {
+		operator := p.previous()
+		if !isAssignable(expr) {
+			return nil, p.error(operator, fmt.Sprintf("Invalid operand for '%s'.", operator.lexeme))
+		}
+		return Increment{target: expr, operator: *operator, prefix: false}, nil
+	}
+
+	return expr, nil
 }
 
-func (p *Parser) expressionStatement() (Statement, error) {
-	expr, err := p.assignment()
-	if err != nil {
-		return nil, err
-	}
-	_, err = p.consume(TOKEN_SEMICOLON, "Expected ';' after expression.")
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
 	if err != nil {
 		return nil, err
 	}
-	return ExpressionStatement{expr}, nil
-}
 
-func (p *Parser) declarationStatement() (Statement, error) {
-	if p.match(TOKEN_VAR) {
-		return p.variableDeclaration()
+	for {
+		if p.match(TOKEN_LEFT_PAREN) {
+			expr, err = p.finishCall(expr)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(TOKEN_DOT, TOKEN_QUESTION_DOT) {
+			optional := p.previous().tokenType == TOKEN_QUESTION_DOT
+			if optional && p.match(TOKEN_LEFT_BRACKET) {
+				expr, err = p.index(expr, true)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			name, err := p.consume(TOKEN_IDENTIFIER, fmt.Sprintf("Expected property name after '%s'.", p.previous().lexeme))
+			if err != nil {
+				return nil, err
+			}
+			expr = Get{object: expr, name: *name, optional: optional}
+		} else if p.match(TOKEN_LEFT_BRACKET) {
+			expr, err = p.index(expr, false)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			break
+		}
 	}
 
-	return p.statement()
+	return expr, nil
 }
 
-func (p *Parser) variableDeclaration() (Statement, error) {
-	name, err := p.consume(TOKEN_IDENTIFIER, "Expect variable name.")
+// index parses the rest of an index expression after its '['. An optional
+// index is written "?.[".
+func (p *Parser) index(object Expr, optional bool) (Expr, error) {
+	bracket := p.previous()
+	index, err := p.argument()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_RIGHT_BRACKET, "Expected ']' after index.")
 	if err != nil {
 		return nil, err
 	}
 
-	var expr Expr
-	if p.match(TOKEN_EQUAL) {
-		expr, err = p.assignment()
-		if err != nil {
-			return nil, err
+	return Index{object: object, bracket: *bracket, index: index, optional: optional}, nil
+}
+
+func (p *Parser) finishCall(callee Expr) (Expr, error) {
+	var arguments []Expr
+	if !p.check(TOKEN_RIGHT_PAREN) {
+		for {
+			argument, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(TOKEN_COMMA) {
+				break
+			}
 		}
 	}
 
-	_, err = p.consume(TOKEN_SEMICOLON, "Expect ';' after variable declaration.")
+	paren, err := p.consume(TOKEN_RIGHT_PAREN, "Expected ')' after arguments.")
 	if err != nil {
 		return nil, err
 	}
 
-	return VarDeclarationStatement{name: *name, initializer: expr}, nil
+	return Call{callee: callee, paren: *paren, arguments: arguments}, nil
 }
 
-func (p *Parser) assignment() (Expr, error) {
-	expr, err := p.expression()
-	if err != nil {
-		return nil, err
+// argument parses a single call argument. The comma operator is switched off
+// while doing so since commas separate the arguments instead.
+func (p *Parser) argument() (Expr, error) {
+	enclosing := p.noComma
+	p.noComma = true
+	defer func() { p.noComma = enclosing }()
+
+	return p.assignment()
+}
+
+func (p *Parser) primary() (Expr, error) {
+	if p.match(TOKEN_FALSE) {
+		return Literal{value: *p.previous()}, nil
+	}
+	if p.match(TOKEN_TRUE) {
+		return Literal{value: *p.previous()}, nil
+	}
+	if p.match(TOKEN_NIL) {
+		return Literal{value: *p.previous()}, nil
 	}
 
-	if p.match(TOKEN_EQUAL) {
-		equals := p.previous()
-		value, err := p.assignment()
+	if p.match(TOKEN_NUMBER, TOKEN_STRING) {
+		return Literal{value: *p.previous()}, nil
+	}
+
+	if p.match(TOKEN_INTERPOLATION) {
+		return p.interpolation()
+	}
+
+	if p.match(TOKEN_THIS) {
+		return This{keyword: *p.previous(), depth: newDepth()}, nil
+	}
+
+	if p.match(TOKEN_SUPER) {
+		keyword := p.previous()
+		_, err := p.consume(TOKEN_DOT, "Expected '.' after 'super'.")
 		if err != nil {
 			return nil, err
 		}
-
-		if varExpr, ok := expr.(Variable); ok {
-			name := varExpr.name
-			return Assign{name, value}, nil
+		method, err := p.consume(TOKEN_IDENTIFIER, "Expected superclass method name.")
+		if err != nil {
+			return nil, err
 		}
+		return Super{keyword: *keyword, method: *method, depth: newDepth()}, nil
+	}
 
-		return nil, fmt.Errorf("Invalid assignment target: %s", equals)
+	if p.match(TOKEN_IDENTIFIER) {
+		return Variable{name: *p.previous(), depth: newDepth()}, nil
 	}
 
-	return expr, nil
-}
+	if p.match(TOKEN_LEFT_BRACKET) {
+		return p.listLiteral()
+	}
 
-func (p *Parser) expression() (Expr, error) {
-	return p.ternary()
-}
+	if p.match(TOKEN_LEFT_BRACE) {
+		return p.mapLiteral()
+	}
 
-func (p *Parser) ternary() (Expr, error) {
-	condition, err := p.block()
-	if err != nil {
-		return nil, err
+	if p.match(TOKEN_MATCH) {
+		return p.matchExpression()
 	}
-	if p.match(TOKEN_QUESTION_MARK) {
-		left, err := p.ternary()
+
+	if p.match(TOKEN_LEFT_PAREN) {
+		// Parentheses bring the comma operator back inside call arguments.
+		enclosing := p.noComma
+		p.noComma = false
+		expr, err := p.expression()
+		p.noComma = enclosing
 		if err != nil {
 			return nil, err
 		}
-		_, err = p.consume(TOKEN_COLON, "Expected ':' in Ternay expression")
+		_, err = p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after expression.")
 		if err != nil {
 			return nil, err
 		}
-		right, err := p.ternary()
-		return Ternary{condition: condition, left: left, right: right}, nil
+		return Grouping{expression: expr}, nil
 	}
 
-	return condition, nil
+	tok := p.peek()
+	return nil, p.error(&tok, "Expected expression.")
 }
 
-func (p *Parser) block() (Expr, error) {
-	expr, err := p.equality()
-	if err != nil {
-		return nil, err
-	}
-	for p.match(TOKEN_COMMA) {
-		operator := p.previous()
-		right, err := p.equality()
+// interpolation parses the expressions in the holes of an interpolated
+// string. The scanner has already split each hole into its own token stream,
+// so each one gets a parser of its own.
+func (p *Parser) interpolation() (Expr, error) {
+	token := p.previous()
+	template := token.literal.(StringTemplate)
+
+	exprs := make([]Expr, 0, len(template.exprs))
+	for _, tokens := range template.exprs {
+		hole := NewParser(tokens, p.errorReporter, p.warningReporter)
+		expr, err := hole.expression()
 		if err != nil {
 			return nil, err
 		}
-		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+		if !hole.isAtEnd() {
+			tok := hole.peek()
+			return nil, hole.error(&tok, "Expected '}' after interpolated expression.")
+		}
+		exprs = append(exprs, expr)
 	}
 
-	return expr, nil
+	return Interpolation{token: *token, parts: template.parts, exprs: exprs}, nil
 }
 
-func (p *Parser) equality() (Expr, error) {
-	expr, err := p.comparison()
-	if err != nil {
-		return nil, err
-	}
+func (p *Parser) listLiteral() (Expr, error) {
+	bracket := p.previous()
 
-	for p.match(TOKEN_BANG_EQUAL, TOKEN_EQUAL_EQUAL) {
-		operator := p.previous()
-		right, err := p.comparison()
+	var elements []Expr
+	for !p.check(TOKEN_RIGHT_BRACKET) && !p.isAtEnd() {
+		element, err := p.argument()
 		if err != nil {
 			return nil, err
 		}
-		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+		elements = append(elements, element)
+		// A trailing comma before the closing bracket is allowed.
+		if !p.match(TOKEN_COMMA) {
+			break
+		}
 	}
 
-	return expr, nil
-}
-
-func (p *Parser) comparison() (Expr, error) {
-	expr, err := p.term()
+	_, err := p.consume(TOKEN_RIGHT_BRACKET, "Expected ']' after list elements.")
 	if err != nil {
 		return nil, err
 	}
 
-	for p.match(TOKEN_GREATER, TOKEN_GREATER_EQUAL, TOKEN_LESS, TOKEN_LESS_EQUAL) {
-		operator := p.previous()
-		right, err := p.term()
+	return ListLiteral{bracket: *bracket, elements: elements}, nil
+}
+
+func (p *Parser) mapLiteral() (Expr, error) {
+	brace := p.previous()
+
+	var keys []Expr
+	var values []Expr
+	for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
+		key, err := p.argument()
 		if err != nil {
 			return nil, err
 		}
+		_, err = p.consume(TOKEN_COLON, "Expected ':' after map key.")
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.argument()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		// A trailing comma before the closing brace is allowed.
+		if !p.match(TOKEN_COMMA) {
+			break
+		}
+	}
 
-		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+	_, err := p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after map entries.")
+	if err != nil {
+		return nil, err
 	}
 
-	return expr, nil
+	return MapLiteral{brace: *brace, keys: keys, values: values}, nil
 }
 
-func (p *Parser) term() (Expr, error) {
-	expr, err := p.factor()
+func (p *Parser) matchExpression() (Expr, error) {
+	keyword := p.previous()
+	_, err := p.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'match'.")
+	if err != nil {
+		return nil, err
+	}
+	enclosing := p.noComma
+	p.noComma = false
+	subject, err := p.expression()
+	p.noComma = enclosing
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after match value.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(TOKEN_LEFT_BRACE, "Expect '{' before match arms.")
 	if err != nil {
 		return nil, err
 	}
 
-	for p.match(TOKEN_MINUS, TOKEN_PLUS) {
-		operator := p.previous()
-		right, err := p.factor()
+	var arms []MatchArm
+	// The first arm without a guard whose pattern matches every value.
+	var catchAll *Token
+	for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
+		arm, err := p.matchArm()
 		if err != nil {
 			return nil, err
 		}
-		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
+
+		start := arm.pattern.start()
+		if catchAll != nil {
+			p.warning(&start, fmt.Sprintf("Unreachable match arm, the arm on line %d matches every value.", catchAll.line))
+		} else if arm.guard == nil && matchesAnything(arm.pattern) {
+			catchAll = &start
+		}
+		arms = append(arms, arm)
+
+		// A trailing comma after the last arm is allowed.
+		if !p.match(TOKEN_COMMA) {
+			break
+		}
 	}
 
-	return expr, nil
+	_, err = p.consume(TOKEN_RIGHT_BRACE, "Expect '}' after match arms.")
+	if err != nil {
+		return nil, err
+	}
+
+	return Match{keyword: *keyword, subject: subject, arms: arms}, nil
 }
 
-func (p *Parser) factor() (Expr, error) {
-	expr, err := p.unary()
+func (p *Parser) matchArm() (MatchArm, error) {
+	pattern, err := p.pattern()
 	if err != nil {
-		return nil, err
+		return MatchArm{}, err
 	}
 
-	for p.match(TOKEN_SLASH, TOKEN_STAR) {
-		operator := p.previous()
-		right, err := p.unary()
+	var guard Expr
+	if p.match(TOKEN_IF) {
+		guard, err = p.argument()
 		if err != nil {
-			return nil, err
+			return MatchArm{}, err
 		}
-		expr = Binary{left: expr, operator: Operator{operator: *operator}, right: right}
 	}
 
-	return expr, nil
+	_, err = p.consume(TOKEN_FAT_ARROW, "Expect '=>' after match pattern.")
+	if err != nil {
+		return MatchArm{}, err
+	}
+	body, err := p.argument()
+	if err != nil {
+		return MatchArm{}, err
+	}
+
+	return MatchArm{pattern: pattern, guard: guard, body: body}, nil
 }
 
-func (p *Parser) unary() (Expr, error) {
-	if p.match(TOKEN_BANG, TOKEN_MINUS) {
-		operator := p.previous()
-		right, err := p.unary()
+func (p *Parser) pattern() (Pattern, error) {
+	pattern, err := p.primaryPattern()
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(TOKEN_PIPE) {
+		return pattern, nil
+	}
+
+	alternatives := []Pattern{pattern}
+	for p.match(TOKEN_PIPE) {
+		alternative, err := p.primaryPattern()
 		if err != nil {
 			return nil, err
 		}
-		return Unary{operator: *operator, right: right}, nil
+		alternatives = append(alternatives, alternative)
 	}
 
-	return p.primary()
+	return AlternativePattern{alternatives: alternatives}, nil
 }
 
-func (p *Parser) primary() (Expr, error) {
-	if p.match(TOKEN_FALSE) {
-		return Literal{value: *p.previous()}, nil
+func (p *Parser) primaryPattern() (Pattern, error) {
+	if p.match(TOKEN_IDENTIFIER) {
+		name := p.previous()
+		if name.lexeme == "_" {
+			return WildcardPattern{underscore: *name}, nil
+		}
+		return BindingPattern{name: *name}, nil
 	}
-	if p.match(TOKEN_TRUE) {
-		return Literal{value: *p.previous()}, nil
+
+	if p.match(TOKEN_LEFT_BRACKET) {
+		return p.listPattern()
 	}
-	if p.match(TOKEN_NIL) {
-		return Literal{value: *p.previous()}, nil
+
+	if p.match(TOKEN_LEFT_BRACE) {
+		return p.mapPattern()
 	}
 
-	if p.match(TOKEN_NUMBER, TOKEN_STRING) {
-		return Literal{value: *p.previous()}, nil
+	return p.literalPattern()
+}
+
+func (p *Parser) listPattern() (Pattern, error) {
+	bracket := p.previous()
+
+	var elements []Pattern
+	for !p.check(TOKEN_RIGHT_BRACKET) && !p.isAtEnd() {
+		element, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.match(TOKEN_COMMA) {
+			break
+		}
 	}
 
-	if p.match(TOKEN_IDENTIFIER) {
-		return Variable{name: *p.previous()}, nil
+	_, err := p.consume(TOKEN_RIGHT_BRACKET, "Expected ']' after list pattern.")
+	if err != nil {
+		return nil, err
 	}
 
-	if p.match(TOKEN_LEFT_PAREN) {
-		expr, err := p.expression()
+	return ListPattern{bracket: *bracket, elements: elements}, nil
+}
+
+func (p *Parser) mapPattern() (Pattern, error) {
+	brace := p.previous()
+
+	var keys []LiteralPattern
+	var values []Pattern
+	for !p.check(TOKEN_RIGHT_BRACE) && !p.isAtEnd() {
+		key, err := p.literalPattern()
 		if err != nil {
 			return nil, err
 		}
-		_, err = p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after expression.")
+		_, err = p.consume(TOKEN_COLON, "Expected ':' after map pattern key.")
 		if err != nil {
 			return nil, err
 		}
-		return Grouping{expression: expr}, nil
+		value, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if !p.match(TOKEN_COMMA) {
+			break
+		}
+	}
+
+	_, err := p.consume(TOKEN_RIGHT_BRACE, "Expected '}' after map pattern.")
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("Expected expression. got %s", p.peek().lexeme)
+	return MapPattern{brace: *brace, keys: keys, values: values}, nil
 }
 
-func (p *Parser) synchronize() {
-	p.advance()
+// literalPattern parses a literal, folding a leading '-' into a negative
+// number so that the pattern needs no evaluating.
+func (p *Parser) literalPattern() (LiteralPattern, error) {
+	if p.match(TOKEN_MINUS) {
+		minus := p.previous()
+		number, err := p.consume(TOKEN_NUMBER, "Expected number after '-' in pattern.")
+		if err != nil {
+			return LiteralPattern{}, err
+		}
+		value, _ := negateNumber(number.literal)
+		return LiteralPattern{value: NewToken(TOKEN_NUMBER, "-"+number.lexeme, value, minus.line, minus.col)}, nil
+	}
+
+	if p.match(TOKEN_NUMBER, TOKEN_STRING, TOKEN_TRUE, TOKEN_FALSE, TOKEN_NIL) {
+		return LiteralPattern{value: *p.previous()}, nil
+	}
+
+	tok := p.peek()
+	return LiteralPattern{}, p.error(&tok, "Expected pattern.")
+}
 
+// synchronize skips tokens until the start of the next statement. Inside a
+// block it stops in front of a '}' so that an error on the last statement of
+// the block does not swallow the end of the block. At the top level there is
+// no block to end, so a '}' left over from the broken statement is skipped.
+func (p *Parser) synchronize() {
 	for !p.isAtEnd() {
 		if p.previous().tokenType == TOKEN_SEMICOLON {
 			return
 		}
 
 		switch p.peek().tokenType {
-		case TOKEN_CLASS, TOKEN_FUN, TOKEN_VAR, TOKEN_FOR, TOKEN_IF, TOKEN_WHILE, TOKEN_PRINT, TOKEN_RETURN:
+		case TOKEN_CLASS, TOKEN_FUN, TOKEN_VAR, TOKEN_CONST, TOKEN_FOR, TOKEN_IF, TOKEN_WHILE, TOKEN_PRINT, TOKEN_RETURN,
+			TOKEN_BREAK, TOKEN_CONTINUE, TOKEN_THROW, TOKEN_TRY, TOKEN_IMPORT, TOKEN_EXPORT:
+			return
+		case TOKEN_RIGHT_BRACE:
+			if p.blockDepth > 0 {
+				return
+			}
+		case TOKEN_LEFT_BRACE:
+			// The rest of a broken statement can hold a whole block, such as
+			// the body of a function with a bad parameter list. Skip it in one
+			// go so its statements aren't taken for ones that follow it, and
+			// carry on after it as if it were the statement's ';'. Braces
+			// that closed whatever held it at the top level go with it.
+			p.skipBraces()
+			for p.blockDepth == 0 && p.check(TOKEN_RIGHT_BRACE) {
+				p.advance()
+			}
 			return
 		}
 
@@ -322,6 +1576,23 @@ func (p *Parser) synchronize() {
 	}
 }
 
+// skipBraces skips from the '{' under the cursor to just past its matching
+// '}'.
+func (p *Parser) skipBraces() {
+	depth := 0
+	for !p.isAtEnd() {
+		switch p.advance().tokenType {
+		case TOKEN_LEFT_BRACE:
+			depth++
+		case TOKEN_RIGHT_BRACE:
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
 func (p *Parser) match(types ...int) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -340,14 +1611,30 @@ func (p *Parser) consume(tokenType int, message string) (*Token, error) {
 
 	tok := p.peek()
 	if p.isAtEnd() {
-		err := fmt.Errorf("Reached unexpected EOF")
-		p.errorReporter(&tok, tok.line, tok.col, err.Error())
-		return nil, err
+		return nil, p.error(&tok, "Reached unexpected EOF")
+	}
+
+	return nil, p.error(&tok, message)
+}
+
+// consumeWord is consume for an identifier that acts as a keyword in one
+// place only, such as "from" in an import.
+func (p *Parser) consumeWord(word string, message string) (*Token, error) {
+	if p.check(TOKEN_IDENTIFIER) && p.peek().lexeme == word {
+		return p.advance(), nil
 	}
 
-	err := fmt.Errorf(message)
-	p.errorReporter(&tok, tok.line, tok.col, err.Error())
-	return nil, fmt.Errorf(message)
+	tok := p.peek()
+	return nil, p.error(&tok, message)
+}
+
+func (p *Parser) error(tok *Token, message string) error {
+	p.errorReporter(tok, tok.line, tok.col, message)
+	return errors.New(message)
+}
+
+func (p *Parser) warning(tok *Token, message string) {
+	p.warningReporter(tok, message)
 }
 
 func (p *Parser) check(tokenType int) bool {
@@ -357,6 +1644,13 @@ func (p *Parser) check(tokenType int) bool {
 	return p.peek().tokenType == tokenType
 }
 
+func (p *Parser) checkNext(tokenType int) bool {
+	if p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].tokenType == tokenType
+}
+
 func (p *Parser) advance() *Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestErrorRecovery(t *testing.T) {
+	runCases(t, []loxCase{
+		{"one message per mistake", `var = 1;
+print 1 +;
+var x = 2
+print x;
+1 = 2;
+if (x print x;`,
+			"error: at '=': Expect variable name.\n  --> test.lox:1:5\n  |\n1 | var = 1;\n  |     ^\n" +
+				"error: at ';': Expected expression.\n  --> test.lox:2:10\n  |\n2 | print 1 +;\n  |          ^\n" +
+				"error: at 'print': Expect ';' after variable declaration.\n  --> test.lox:4:1\n  |\n4 | print x;\n  | ^~~~~\n" +
+				"error: at '=': Invalid assignment target.\n  --> test.lox:5:3\n  |\n5 | 1 = 2;\n  |   ^\n" +
+				"error: at 'print': Expected ')' after if condition.\n  --> test.lox:6:7\n  |\n6 | if (x print x;\n  |       ^~~~~\n"},
+		{"errors inside blocks", `{ print ; } { var 1; }`,
+			"error: at ';': Expected expression.\n  --> test.lox:1:9\n  |\n1 | { print ; } { var 1; }\n  |         ^\n" +
+				"error: at '1': Expect variable name.\n  --> test.lox:1:19\n  |\n1 | { print ; } { var 1; }\n  |                   ^\n"},
+		{"recovers at the end of a block", `fun f() { print 1 +; } print "after";`,
+			"error: at ';': Expected expression.\n  --> test.lox:1:20\n  |\n1 | fun f() { print 1 +; } print \"after\";\n  |                    ^\n"},
+		{"keeps going after a bad statement keyword", `while print 1; print 2 +;`,
+			"error: at 'print': Expected '(' after 'while'.\n  --> test.lox:1:7\n  |\n1 | while print 1; print 2 +;\n  |       ^~~~~\n" +
+				"error: at ';': Expected expression.\n  --> test.lox:1:25\n  |\n1 | while print 1; print 2 +;\n  |                         ^\n"},
+		{"one message per mistake in braced code", `fun f(1) { print 1; }
+class A { m( { } }
+while (true { print 1; }
+{ fun g( { print 1; } }
+print 2 +;`,
+			"error: at '1': Expected parameter name.\n  --> test.lox:1:7\n  |\n1 | fun f(1) { print 1; }\n  |       ^\n" +
+				"error: at '{': Expected parameter name.\n  --> test.lox:2:14\n  |\n2 | class A { m( { } }\n  |              ^\n" +
+				"error: at '{': Expected ')' after while condition.\n  --> test.lox:3:13\n  |\n3 | while (true { print 1; }\n  |             ^\n" +
+				"error: at '{': Expected parameter name.\n  --> test.lox:4:10\n  |\n4 | { fun g( { print 1; } }\n  |          ^\n" +
+				"error: at ';': Expected expression.\n  --> test.lox:5:10\n  |\n5 | print 2 +;\n  |          ^\n"},
+		{"a stray brace at the top level is reported once", "}\nprint 1 +;",
+			"error: at '}': Expected expression.\n  --> test.lox:1:1\n  |\n1 | }\n  | ^\n" +
+				"error: at ';': Expected expression.\n  --> test.lox:2:10\n  |\n2 | print 1 +;\n  |          ^\n"},
+		{"unterminated block", `{ print 1;`,
+			"error: at end: Reached unexpected EOF\n  --> test.lox:1:10\n  |\n1 | { print 1;\n  |          ^\n"},
+		{"nothing runs when there is an error", `print "before"; print +;`,
+			"error: at '+': Expected expression.\n  --> test.lox:1:23\n  |\n1 | print \"before\"; print +;\n  |                       ^\n"},
+	})
+}
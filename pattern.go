@@ -0,0 +1,156 @@
+package main
+
+// Pattern is the left hand side of an arm of a match expression. Patterns
+// are tested against a value rather than evaluated, so they are walked with a
+// type switch instead of through ExprVisitor.
+type Pattern interface {
+	// start is the first token of the pattern, for diagnostics.
+	start() Token
+}
+
+// LiteralPattern matches values equal to a number, string, boolean or nil
+// literal. A negative number is held as a single token.
+type LiteralPattern struct {
+	value Token
+}
+
+func (l LiteralPattern) start() Token {
+	return l.value
+}
+
+// WildcardPattern, written "_", matches anything without binding it.
+type WildcardPattern struct {
+	underscore Token
+}
+
+func (w WildcardPattern) start() Token {
+	return w.underscore
+}
+
+// BindingPattern matches anything and binds it to name.
+type BindingPattern struct {
+	name Token
+}
+
+func (b BindingPattern) start() Token {
+	return b.name
+}
+
+// ListPattern matches a list of exactly as many elements as it has, each
+// matching the pattern in the same position.
+type ListPattern struct {
+	bracket  Token
+	elements []Pattern
+}
+
+func (l ListPattern) start() Token {
+	return l.bracket
+}
+
+// MapPattern matches a map holding every one of its keys with a value
+// matching the pattern given for it. Other entries in the map are ignored.
+type MapPattern struct {
+	brace  Token
+	keys   []LiteralPattern
+	values []Pattern
+}
+
+func (m MapPattern) start() Token {
+	return m.brace
+}
+
+// AlternativePattern, written with "|", matches when any one of its
+// alternatives does. Every alternative binds the same names.
+type AlternativePattern struct {
+	alternatives []Pattern
+}
+
+func (a AlternativePattern) start() Token {
+	return a.alternatives[0].start()
+}
+
+// matchPattern tests value against pattern, defining the names the pattern
+// binds in env as it goes. env is only meaningful when the match succeeds.
+func matchPattern(pattern Pattern, value any, env *Environment) bool {
+	switch pattern := pattern.(type) {
+	case LiteralPattern:
+		return isEqual(pattern.value.literal, value)
+	case WildcardPattern:
+		return true
+	case BindingPattern:
+		env.define(pattern.name.lexeme, value)
+		return true
+	case ListPattern:
+		list, ok := value.(*LoxList)
+		if !ok || len(list.elements) != len(pattern.elements) {
+			return false
+		}
+		for index, element := range pattern.elements {
+			if !matchPattern(element, list.elements[index], env) {
+				return false
+			}
+		}
+		return true
+	case MapPattern:
+		m, ok := value.(*LoxMap)
+		if !ok {
+			return false
+		}
+		for index, key := range pattern.keys {
+			entry, ok := m.get(key.value.literal)
+			if !ok || !matchPattern(pattern.values[index], entry, env) {
+				return false
+			}
+		}
+		return true
+	case AlternativePattern:
+		for _, alternative := range pattern.alternatives {
+			if matchPattern(alternative, value, env) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// matchesAnything reports whether pattern matches every value, which makes
+// any arm after it unreachable.
+func matchesAnything(pattern Pattern) bool {
+	switch pattern := pattern.(type) {
+	case WildcardPattern, BindingPattern:
+		return true
+	case AlternativePattern:
+		for _, alternative := range pattern.alternatives {
+			if matchesAnything(alternative) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patternBindings returns the names pattern binds, in the order they are
+// written.
+func patternBindings(pattern Pattern) []Token {
+	switch pattern := pattern.(type) {
+	case BindingPattern:
+		return []Token{pattern.name}
+	case ListPattern:
+		var names []Token
+		for _, element := range pattern.elements {
+			names = append(names, patternBindings(element)...)
+		}
+		return names
+	case MapPattern:
+		var names []Token
+		for _, value := range pattern.values {
+			names = append(names, patternBindings(value)...)
+		}
+		return names
+	case AlternativePattern:
+		// The resolver checks that the other alternatives bind the same names.
+		return patternBindings(pattern.alternatives[0])
+	}
+	return nil
+}
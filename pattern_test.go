@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	describe := `fun describe(v) {
+  return match (v) {
+    1 | 2 => "small",
+    -1 => "minus one",
+    "hi" => "greeting",
+    [a, b] => "pair ${a} ${b}",
+    [] => "empty",
+    {"k": v, "n": [x, _]} => "map ${v} ${x}",
+    nil => "nothing",
+    n if n > 10 => "big ${n}",
+    _ => "other",
+  };
+}
+`
+	runCases(t, []loxCase{
+		{"alternatives", describe + "print describe(2);", "small\n"},
+		{"numbers compare by value", describe + "print describe(1.0);", "small\n"},
+		{"negative literal", describe + "print describe(-1);", "minus one\n"},
+		{"string", describe + `print describe("hi");`, "greeting\n"},
+		{"list binds elements", describe + "print describe([3, 4]);", "pair 3 4\n"},
+		{"list length must match", "print match ([3, 4, 5]) { [a, b] => a, _ => 0 };", "0\n"},
+		{"empty list", describe + "print describe([]);", "empty\n"},
+		{"map ignores other keys", describe + `print describe({"k": "v", "n": [7, 8], "extra": 1});`, "map v 7\n"},
+		{"map needs every key", `print match ({"k": "v"}) { {"k": k, "n": n} => k, _ => 0 };`, "0\n"},
+		{"nil", describe + "print describe(nil);", "nothing\n"},
+		{"guard", describe + "print describe(42);", "big 42\n"},
+		{"guard fails", describe + "print describe(5);", "other\n"},
+		{"bindings stay in their arm", "var x = 1; print match (2) { x if x > 5 => x, y => x + y };", "3\n"},
+		{"is an expression", "print match (3) { n => n * 2 } + 1;", "7\n"},
+		{"no arm matches", `print match (99) { 1 => "one" };`,
+			"error: No match arm matched 99.\n  --> test.lox:1:7\n  |\n1 | print match (99) { 1 => \"one\" };\n  |       ^~~~~\n"},
+		{"unreachable arm", "print match (1) {\n  _ => 1,\n  2 => 2\n};",
+			"warning: Unreachable match arm, the arm on line 2 matches every value.\n  --> test.lox:3:3\n  |\n3 |   2 => 2\n  |   ^\n1\n"},
+		{"first matching arm wins", "print match (1) { 1 => \"first\", 1 => \"second\" };", "first\n"},
+		{"arm after a binding is unreachable", "print match (1) { n => n, 2 => 2 };",
+			"warning: Unreachable match arm, the arm on line 1 matches every value.\n  --> test.lox:1:27\n  |\n1 | print match (1) { n => n, 2 => 2 };\n  |                           ^\n1\n"},
+		{"guard errors are reported", "print match (1) { n if n < \"a\" => n, _ => 0 };",
+			"error: Unexpected values for operator: <\n  --> test.lox:1:26\n  |\n1 | print match (1) { n if n < \"a\" => n, _ => 0 };\n  |                          ^\n"},
+		{"name bound twice", "print match ([1, 2]) { [a, a] => a };",
+			"error: at 'a': Already a variable named 'a' in this scope.\n  --> test.lox:1:28\n  |\n1 | print match ([1, 2]) { [a, a] => a };\n  |                            ^\n"},
+		{"alternatives bind the same names", "print match ([1]) { [a] | [b] => 1 };",
+			"error: at '[': Every alternative of a pattern must bind the same names.\n  --> test.lox:1:27\n  |\n1 | print match ([1]) { [a] | [b] => 1 };\n  |                           ^\n"},
+		{"missing arrow", `print match (1) { 1 "one" };`,
+			"error: at '\"one\"': Expect '=>' after match pattern.\n  --> test.lox:1:21\n  |\n1 | print match (1) { 1 \"one\" };\n  |                     ^~~~~\n"},
+		{"map pattern keys are literals", `print match ({}) { {a: 1} => 1 };`,
+			"error: at 'a': Expected pattern.\n  --> test.lox:1:21\n  |\n1 | print match ({}) { {a: 1} => 1 };\n  |                     ^\n"},
+	})
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+const (
+	FUNCTION_NONE = iota
+	FUNCTION_FUNCTION
+	FUNCTION_INITIALIZER
+	FUNCTION_METHOD
+)
+
+const (
+	CLASS_NONE = iota
+	CLASS_CLASS
+	CLASS_SUBCLASS
+)
+
+// Resolver walks the parsed program before it runs and records, for every
+// variable reference, how many scopes separate it from its declaration.
+// Globals are left unresolved and looked up by name at runtime.
+type Resolver struct {
+	// Each scope maps a name to whether its initializer has finished.
+	scopes []map[string]bool
+	// The constants declared in each scope, and at the top level, by the
+	// name token of their declaration.
+	constants       []map[string]Token
+	globalConstants map[string]Token
+	currentFunction int
+	currentClass    int
+	errorReporter   func(*Token, int, int, string)
+	noteReporter    func(*Token, string)
+	errors          []error
+}
+
+func NewResolver(reportError func(*Token, int, int, string), reportNote func(*Token, string)) Resolver {
+	return Resolver{
+		scopes:          make([]map[string]bool, 0),
+		globalConstants: make(map[string]Token),
+		currentFunction: FUNCTION_NONE,
+		currentClass:    CLASS_NONE,
+		errorReporter:   reportError,
+		noteReporter:    reportNote,
+	}
+}
+
+func (r *Resolver) resolve(stmts []Statement) error {
+	r.resolveStatements(stmts)
+	return errors.Join(r.errors...)
+}
+
+func (r *Resolver) resolveStatements(stmts []Statement) {
+	for _, stmt := range stmts {
+		r.resolveStatement(stmt)
+	}
+}
+
+func (r *Resolver) resolveStatement(stmt Statement) {
+	stmt.accept(r)
+}
+
+func (r *Resolver) resolveExpr(expr Expr) {
+	expr.accept(r)
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+	r.constants = append(r.constants, make(map[string]Token))
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.constants = r.constants[:len(r.constants)-1]
+}
+
+func (r *Resolver) declare(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.lexeme]; ok {
+		r.error(&name, fmt.Sprintf("Already a variable named '%s' in this scope.", name.lexeme))
+	}
+	scope[name.lexeme] = false
+}
+
+func (r *Resolver) define(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.lexeme] = true
+}
+
+// markConstant records whether the name just declared in the innermost
+// scope is a constant. At the top level a var can redeclare a constant.
+func (r *Resolver) markConstant(name Token, constant bool) {
+	constants := r.globalConstants
+	if len(r.scopes) > 0 {
+		constants = r.constants[len(r.constants)-1]
+	}
+
+	if constant {
+		constants[name.lexeme] = name
+	} else {
+		delete(constants, name.lexeme)
+	}
+}
+
+// checkAssignable reports an assignment to a name that resolves to a
+// constant, pointing at both the assignment and the declaration.
+func (r *Resolver) checkAssignable(name Token) {
+	declaration, ok := r.globalConstants[name.lexeme]
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, found := r.scopes[i][name.lexeme]; found {
+			declaration, ok = r.constants[i][name.lexeme]
+			break
+		}
+	}
+	if !ok {
+		return
+	}
+
+	r.error(&name, fmt.Sprintf("Can't assign to constant '%s'.", name.lexeme))
+	r.noteReporter(&declaration, fmt.Sprintf("'%s' is declared as a constant here.", name.lexeme))
+}
+
+func (r *Resolver) resolveLocal(name Token, depth *int) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][name.lexeme]; ok {
+			*depth = len(r.scopes) - 1 - i
+			return
+		}
+	}
+}
+
+func (r *Resolver) resolveFunction(function FunctionStatement, functionType int) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = functionType
+	defer func() { r.currentFunction = enclosingFunction }()
+
+	r.beginScope()
+	for _, param := range function.params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStatements(function.body)
+	r.endScope()
+}
+
+func (r *Resolver) error(token *Token, message string) {
+	r.errorReporter(token, token.line, token.col, message)
+	r.errors = append(r.errors, errors.New(message))
+}
+
+func (r *Resolver) visitBlockStatement(stmt BlockStatement) (any, error) {
+	r.beginScope()
+	r.resolveStatements(stmt.stmts)
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) visitVarDeclarationStatement(stmt VarDeclarationStatement) (any, error) {
+	r.declare(stmt.name)
+	if stmt.initializer != nil {
+		r.resolveExpr(stmt.initializer)
+	}
+	r.define(stmt.name)
+	r.markConstant(stmt.name, stmt.constant)
+	return nil, nil
+}
+
+func (r *Resolver) visitFunctionStatement(stmt FunctionStatement) (any, error) {
+	// The name is defined straight away so the function can recurse.
+	r.declare(stmt.name)
+	r.define(stmt.name)
+	r.resolveFunction(stmt, FUNCTION_FUNCTION)
+	return nil, nil
+}
+
+func (r *Resolver) visitClassStatement(stmt ClassStatement) (any, error) {
+	enclosingClass := r.currentClass
+	r.currentClass = CLASS_CLASS
+	defer func() { r.currentClass = enclosingClass }()
+
+	r.declare(stmt.name)
+	r.define(stmt.name)
+
+	if stmt.superclass != nil {
+		if stmt.superclass.name.lexeme == stmt.name.lexeme {
+			r.error(&stmt.superclass.name, "A class can't inherit from itself.")
+		}
+		r.currentClass = CLASS_SUBCLASS
+		r.resolveExpr(*stmt.superclass)
+
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["super"] = true
+		defer r.endScope()
+	}
+
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["this"] = true
+	for _, method := range stmt.methods {
+		functionType := FUNCTION_METHOD
+		if method.name.lexeme == "init" {
+			functionType = FUNCTION_INITIALIZER
+		}
+		r.resolveFunction(method, functionType)
+	}
+	r.endScope()
+
+	return nil, nil
+}
+
+func (r *Resolver) visitExpressionStatemet(stmt ExpressionStatement) (any, error) {
+	r.resolveExpr(stmt.expr)
+	return nil, nil
+}
+
+func (r *Resolver) visitPrintStatement(stmt PrintStatement) (any, error) {
+	r.resolveExpr(stmt.expr)
+	return nil, nil
+}
+
+func (r *Resolver) visitIfStatement(stmt IfStatement) (any, error) {
+	r.resolveExpr(stmt.condition)
+	r.resolveStatement(stmt.thenBranch)
+	if stmt.elseBranch != nil {
+		r.resolveStatement(stmt.elseBranch)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitWhileStatement(stmt WhileStatement) (any, error) {
+	r.resolveExpr(stmt.condition)
+	r.resolveStatement(stmt.body)
+	return nil, nil
+}
+
+func (r *Resolver) visitForStatement(stmt ForStatement) (any, error) {
+	// The initializer gets its own scope, mirroring the environment the
+	// interpreter creates for the loop variables.
+	r.beginScope()
+	if stmt.initializer != nil {
+		r.resolveStatement(stmt.initializer)
+	}
+	if stmt.condition != nil {
+		r.resolveExpr(stmt.condition)
+	}
+	if stmt.increment != nil {
+		r.resolveExpr(stmt.increment)
+	}
+	r.resolveStatement(stmt.body)
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) visitBreakStatement(stmt BreakStatement) (any, error) {
+	return nil, nil
+}
+
+func (r *Resolver) visitContinueStatement(stmt ContinueStatement) (any, error) {
+	return nil, nil
+}
+
+func (r *Resolver) visitReturnStatement(stmt ReturnStatement) (any, error) {
+	if stmt.value != nil {
+		if r.currentFunction == FUNCTION_INITIALIZER {
+			r.error(&stmt.keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.value)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitThrowStatement(stmt ThrowStatement) (any, error) {
+	r.resolveExpr(stmt.value)
+	return nil, nil
+}
+
+func (r *Resolver) visitTryStatement(stmt TryStatement) (any, error) {
+	r.resolveStatement(stmt.body)
+	if stmt.catchBody != nil {
+		// The caught value lives in a scope of its own around the block.
+		r.beginScope()
+		r.declare(*stmt.catchName)
+		r.define(*stmt.catchName)
+		r.resolveStatement(*stmt.catchBody)
+		r.endScope()
+	}
+	if stmt.finallyBody != nil {
+		r.resolveStatement(*stmt.finallyBody)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitImportStatement(stmt ImportStatement) (any, error) {
+	if stmt.alias != nil {
+		r.declare(*stmt.alias)
+		r.define(*stmt.alias)
+	}
+	for _, name := range stmt.names {
+		r.declare(name)
+		r.define(name)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitExportStatement(stmt ExportStatement) (any, error) {
+	if len(r.scopes) > 0 {
+		r.error(&stmt.keyword, "Only top-level declarations can be exported.")
+	}
+	r.resolveStatement(stmt.declaration)
+	return nil, nil
+}
+
+func (r *Resolver) visitAssign(expr Assign) (any, error) {
+	r.resolveExpr(expr.value)
+	r.checkAssignable(expr.name)
+	r.resolveLocal(expr.name, expr.depth)
+	return nil, nil
+}
+
+func (r *Resolver) visitVariable(expr Variable) (any, error) {
+	if len(r.scopes) > 0 {
+		if defined, ok := r.scopes[len(r.scopes)-1][expr.name.lexeme]; ok && !defined {
+			r.error(&expr.name, "Can't read local variable in its own initializer.")
+		}
+	}
+
+	r.resolveLocal(expr.name, expr.depth)
+	return nil, nil
+}
+
+func (r *Resolver) visitCompoundAssign(expr CompoundAssign) (any, error) {
+	r.resolveExpr(expr.value)
+	if variable, ok := expr.target.(Variable); ok {
+		r.checkAssignable(variable.name)
+	}
+	r.resolveExpr(expr.target)
+	return nil, nil
+}
+
+func (r *Resolver) visitIncrement(expr Increment) (any, error) {
+	if variable, ok := expr.target.(Variable); ok {
+		r.checkAssignable(variable.name)
+	}
+	r.resolveExpr(expr.target)
+	return nil, nil
+}
+
+func (r *Resolver) visitTernary(expr Ternary) (any, error) {
+	r.resolveExpr(expr.condition)
+	r.resolveExpr(expr.left)
+	r.resolveExpr(expr.right)
+	return nil, nil
+}
+
+func (r *Resolver) visitBinary(expr Binary) (any, error) {
+	r.resolveExpr(expr.left)
+	r.resolveExpr(expr.right)
+	return nil, nil
+}
+
+func (r *Resolver) visitLogical(expr Logical) (any, error) {
+	r.resolveExpr(expr.left)
+	r.resolveExpr(expr.right)
+	return nil, nil
+}
+
+func (r *Resolver) visitCall(expr Call) (any, error) {
+	r.resolveExpr(expr.callee)
+	for _, argument := range expr.arguments {
+		r.resolveExpr(argument)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitGet(expr Get) (any, error) {
+	r.resolveExpr(expr.object)
+	return nil, nil
+}
+
+func (r *Resolver) visitSet(expr Set) (any, error) {
+	r.resolveExpr(expr.value)
+	r.resolveExpr(expr.object)
+	return nil, nil
+}
+
+func (r *Resolver) visitThis(expr This) (any, error) {
+	if r.currentClass == CLASS_NONE {
+		r.error(&expr.keyword, "Can't use 'this' outside of a class.")
+		return nil, nil
+	}
+
+	r.resolveLocal(expr.keyword, expr.depth)
+	return nil, nil
+}
+
+func (r *Resolver) visitSuper(expr Super) (any, error) {
+	if r.currentClass == CLASS_NONE {
+		r.error(&expr.keyword, "Can't use 'super' outside of a class.")
+	} else if r.currentClass != CLASS_SUBCLASS {
+		r.error(&expr.keyword, "Can't use 'super' in a class with no superclass.")
+	}
+
+	r.resolveLocal(expr.keyword, expr.depth)
+	return nil, nil
+}
+
+func (r *Resolver) visitInterpolation(expr Interpolation) (any, error) {
+	for _, hole := range expr.exprs {
+		r.resolveExpr(hole)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitListLiteral(expr ListLiteral) (any, error) {
+	for _, element := range expr.elements {
+		r.resolveExpr(element)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitMapLiteral(expr MapLiteral) (any, error) {
+	for index, key := range expr.keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.values[index])
+	}
+	return nil, nil
+}
+
+func (r *Resolver) visitIndex(expr Index) (any, error) {
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil, nil
+}
+
+func (r *Resolver) visitMatch(expr Match) (any, error) {
+	r.resolveExpr(expr.subject)
+	for _, arm := range expr.arms {
+		// The names an arm binds are only visible in its guard and body.
+		r.beginScope()
+		r.checkAlternatives(arm.pattern)
+		for _, name := range patternBindings(arm.pattern) {
+			r.declare(name)
+			r.define(name)
+		}
+		if arm.guard != nil {
+			r.resolveExpr(arm.guard)
+		}
+		r.resolveExpr(arm.body)
+		r.endScope()
+	}
+	return nil, nil
+}
+
+// checkAlternatives reports alternatives that don't bind the same names as
+// the first one, as the body could otherwise read a name that was never
+// bound.
+func (r *Resolver) checkAlternatives(pattern Pattern) {
+	switch pattern := pattern.(type) {
+	case ListPattern:
+		for _, element := range pattern.elements {
+			r.checkAlternatives(element)
+		}
+	case MapPattern:
+		for _, value := range pattern.values {
+			r.checkAlternatives(value)
+		}
+	case AlternativePattern:
+		expected := make(map[string]bool)
+		for _, name := range patternBindings(pattern.alternatives[0]) {
+			expected[name.lexeme] = true
+		}
+		for _, alternative := range pattern.alternatives {
+			r.checkAlternatives(alternative)
+
+			names := make(map[string]bool)
+			for _, name := range patternBindings(alternative) {
+				names[name.lexeme] = true
+			}
+			if !maps.Equal(names, expected) {
+				start := alternative.start()
+				r.error(&start, "Every alternative of a pattern must bind the same names.")
+			}
+		}
+	}
+}
+
+func (r *Resolver) visitSetIndex(expr SetIndex) (any, error) {
+	r.resolveExpr(expr.value)
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil, nil
+}
+
+func (r *Resolver) visitGrouping(expr Grouping) (any, error) {
+	r.resolveExpr(expr.expression)
+	return nil, nil
+}
+
+func (r *Resolver) visitLiteral(expr Literal) (any, error) {
+	return nil, nil
+}
+
+func (r *Resolver) visitOperator(expr Operator) (any, error) {
+	return nil, nil
+}
+
+func (r *Resolver) visitUnary(expr Unary) (any, error) {
+	r.resolveExpr(expr.right)
+	return nil, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestResolver(t *testing.T) {
+	runCases(t, []loxCase{
+		{"closure keeps its binding when shadowed later", `var a = "global";
+{ fun show() { print a; } show(); var a = "block"; show(); }`,
+			"global\nglobal\n"},
+		{"globals are still late bound", `fun f() { return later; } var later = "late"; print f();`,
+			"late\n"},
+		{"parameters", `fun f(a) { { var b = a; print b; } } f("param");`, "param\n"},
+		{"global may read itself", `var a = "a"; var a = a + "!"; print a;`, "a!\n"},
+		{"local read in its own initializer", `{ var a = a; }`,
+			"error: at 'a': Can't read local variable in its own initializer.\n  --> test.lox:1:11\n  |\n1 | { var a = a; }\n  |           ^\n"},
+		{"local redeclared in the same scope", `{ var a = 1; var a = 2; }`,
+			"error: at 'a': Already a variable named 'a' in this scope.\n  --> test.lox:1:18\n  |\n1 | { var a = 1; var a = 2; }\n  |                  ^\n"},
+		{"parameter redeclared", `fun f(a, a) {}`,
+			"error: at 'a': Already a variable named 'a' in this scope.\n  --> test.lox:1:10\n  |\n1 | fun f(a, a) {}\n  |          ^\n"},
+		{"every error is reported", `{ var a = 1; var a = 2; var b = b; }`,
+			"error: at 'a': Already a variable named 'a' in this scope.\n  --> test.lox:1:18\n  |\n1 | { var a = 1; var a = 2; var b = b; }\n  |                  ^\n" +
+				"error: at 'b': Can't read local variable in its own initializer.\n  --> test.lox:1:33\n  |\n1 | { var a = 1; var a = 2; var b = b; }\n  |                                 ^\n"},
+		{"nothing runs after an error", `print "before"; { var a = a; }`,
+			"error: at 'a': Can't read local variable in its own initializer.\n  --> test.lox:1:27\n  |\n1 | print \"before\"; { var a = a; }\n  |                           ^\n"},
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import "fmt"
+
+// Frame is an entry on the interpreter's stack of active blocks and calls,
+// remembered by the token where it was entered.
+type Frame struct {
+	name  string
+	token Token
+}
+
+// RuntimeError is raised while executing a program. It points at the token
+// being evaluated when things went wrong and keeps a snapshot of the frames
+// that were active at that moment. A throw statement raises one too, with
+// thrown set and the thrown value in value.
+type RuntimeError struct {
+	token   Token
+	message string
+	stack   []Frame
+	thrown  bool
+	value   any
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d, col %d] %s", e.token.line, e.token.col, e.message)
+}
+
+// errorClass is the class of the values a catch clause receives for errors
+// raised by the interpreter itself, such as a division by zero. Programs see
+// it as the global Error, which they can call with a message and subclass.
+var errorClass = newErrorClass()
+
+// newErrorClass builds the Error class with the initializer
+//
+//	init(message) { this.message = message; }
+//
+// written out as the tree the parser and resolver would produce for it.
+func newErrorClass() *LoxClass {
+	message := NewToken(TOKEN_IDENTIFIER, "message", nil, 0, 0)
+	// "this" is bound in the scope just outside the parameters.
+	thisDepth, messageDepth := 1, 0
+	init := FunctionStatement{
+		name:   NewToken(TOKEN_IDENTIFIER, "init", nil, 0, 0),
+		params: []Token{message},
+		body: []Statement{ExpressionStatement{expr: Set{
+			object: This{keyword: NewToken(TOKEN_THIS, "this", nil, 0, 0), depth: &thisDepth},
+			name:   message,
+			value:  Variable{name: message, depth: &messageDepth},
+		}}},
+	}
+
+	return &LoxClass{name: "Error", methods: map[string]LoxFunction{
+		"init": {declaration: init, isInitializer: true},
+	}}
+}
+
+// caughtValue returns what a catch clause binds for err: the thrown value,
+// or an Error instance describing an error raised by the interpreter. The
+// column is one based, as in diagnostics.
+func caughtValue(err *RuntimeError) any {
+	if err.thrown {
+		return err.value
+	}
+
+	return &LoxInstance{class: errorClass, fields: map[string]any{
+		"message": err.message,
+		"line":    int64(err.token.line),
+		"column":  int64(err.token.col + 1),
+	}}
+}
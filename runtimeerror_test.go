@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestRuntimeErrors(t *testing.T) {
+	runCases(t, []loxCase{
+		{"division by zero", `print 1 / 0;`,
+			"error: Division by zero\n  --> test.lox:1:9\n  |\n1 | print 1 / 0;\n  |         ^\n"},
+		{"negate a string", `print -"str";`,
+			"error: Unexpected values for operator: -\n  --> test.lox:1:7\n  |\n1 | print -\"str\";\n  |       ^\n"},
+		{"add mismatched operands", `print "n=" + 1;`,
+			"error: Unexpected values for operator: +\n  --> test.lox:1:12\n  |\n1 | print \"n=\" + 1;\n  |            ^\n"},
+		{"compare mismatched operands", `print 1 < "2";`,
+			"error: Unexpected values for operator: <\n  --> test.lox:1:9\n  |\n1 | print 1 < \"2\";\n  |         ^\n"},
+		{"undefined variable", `print missing;`,
+			"error: Undefined variable : missing\n  --> test.lox:1:7\n  |\n1 | print missing;\n  |       ^~~~~~~\n"},
+		{"assign to an undefined variable", `missing = 1;`,
+			"error: Undefined variable : missing\n  --> test.lox:1:1\n  |\n1 | missing = 1;\n  | ^~~~~~~\n"},
+		{"nothing after the error runs", `print "before"; print 1 / 0; print "after";`,
+			"before\n" +
+				"error: Division by zero\n  --> test.lox:1:25\n  |\n1 | print \"before\"; print 1 / 0; print \"after\";\n  |                         ^\n"},
+		{"frames of blocks and calls", `fun inner() { { return 1 / 0; } }
+fun outer() { return inner(); }
+outer();`,
+			"error: Division by zero\n  --> test.lox:1:26\n  |\n1 | fun inner() { { return 1 / 0; } }\n  |                          ^\n  = in block at test.lox:1:15\n  = in call to <fn inner> at test.lox:2:28\n  = in call to <fn outer> at test.lox:3:7\n"},
+		{"frames are popped on return", `fun f() { return 1; } f(); print -nil;`,
+			"error: Unexpected values for operator: -\n  --> test.lox:1:34\n  |\n1 | fun f() { return 1; } f(); print -nil;\n  |                                  ^\n"},
+	})
+}
+
+func TestExceptions(t *testing.T) {
+	runCases(t, []loxCase{
+		{"catch thrown value", `try { throw "oops"; } catch (e) { print e; }`, "oops\n"},
+		{"catch runtime error", `try { nil.x; } catch (e) { print e.message; print e.line; print e.column; }`,
+			"Only instances and modules have properties.\n1\n11\n"},
+		{"catch undefined variable", `try { print missing; } catch (e) { print e.message; }`,
+			"Undefined variable : missing\n"},
+		{"catch division by zero", `try { print 1 / 0; } catch (e) { print e.message; }`,
+			"Division by zero\n"},
+		{"catch from a called function", `fun f() { throw "deep"; } try { f(); print "skipped"; } catch (e) { print e; }`,
+			"deep\n"},
+		{"code after the try runs", `try { throw 1; } catch (e) {} print "after";`, "after\n"},
+		{"finally always runs", `try { print "body"; } finally { print "finally"; }`, "body\nfinally\n"},
+		{"finally after catch", `try { throw 1; } catch (e) { print "caught"; } finally { print "finally"; }`,
+			"caught\nfinally\n"},
+		{"finally on return", `fun f() { try { return 1; } finally { print "finally"; } } print f();`,
+			"finally\n1\n"},
+		{"finally on break", `while (true) { try { break; } finally { print "finally"; } } print "after";`,
+			"finally\nafter\n"},
+		{"finally on continue", `for (var i = 0; i < 2; i++) { try { continue; } finally { print i; } }`,
+			"0\n1\n"},
+		{"finally on labelled break", `outer: while (true) { while (true) { try { break outer; } finally { print "inner"; } } } print "after";`,
+			"inner\nafter\n"},
+		{"finally on labelled continue", `outer: for (var i = 0; i < 2; i++) { while (true) { try { continue outer; } finally { print i; } } }`,
+			"0\n1\n"},
+		{"finally without catch rethrows", `try { try { throw "up"; } finally { print "finally"; } } catch (e) { print e; }`,
+			"finally\nup\n"},
+		{"return in finally wins", `fun f() { try { throw "lost"; } finally { return "finally"; } } print f();`,
+			"finally\n"},
+		{"throw in catch still runs finally", `try { try { throw 1; } catch (e) { throw e + 1; } finally { print "finally"; } } catch (e) { print e; }`,
+			"finally\n2\n"},
+		{"call Error", `var e = Error("boom"); print e.message;`, "boom\n"},
+		{"throw Error", `try { throw Error("thrown"); } catch (e) { print e.message; }`, "thrown\n"},
+		{"subclass Error", `class NotFound < Error { init(name) { super.init("No " + name); this.name = name; } }
+try { throw NotFound("cat"); } catch (e) { print e.message; print e.name; }`, "No cat\ncat\n"},
+		{"caught runtime errors are Errors", `try { 1 / 0; } catch (e) { print e; }`, "Error instance\n"},
+		{"Error can be shadowed", `var Error = 1; print Error;`, "1\n"},
+		{"Error arity", `Error();`,
+			"error: Expected 1 arguments but got 0.\n  --> test.lox:1:7\n  |\n1 | Error();\n  |       ^\n"},
+		{"uncaught Error", `throw Error("bad");`,
+			"error: bad\n  --> test.lox:1:1\n  |\n1 | throw Error(\"bad\");\n  | ^~~~~\n"},
+		{"catch variable is scoped to the catch", `try { throw 1; } catch (e) {} print e;`,
+			"error: Undefined variable : e\n  --> test.lox:1:37\n  |\n1 | try { throw 1; } catch (e) {} print e;\n  |                                     ^\n"},
+		{"uncaught value", `throw 42;`,
+			"error: Uncaught exception: 42\n  --> test.lox:1:1\n  |\n1 | throw 42;\n  | ^~~~~\n"},
+		{"uncaught from a function", `fun f() { throw "x"; }
+f();`,
+			"error: Uncaught exception: \"x\"\n  --> test.lox:1:11\n  |\n1 | fun f() { throw \"x\"; }\n  |           ^~~~~\n  = in call to <fn f> at test.lox:2:3\n"},
+		{"try needs catch or finally", `try { }`,
+			"error: at 'try': Expected 'catch' or 'finally' after try block.\n  --> test.lox:1:1\n  |\n1 | try { }\n  | ^~~\n"},
+		{"catch needs a name", `try { } catch { print 1; }`,
+			"error: at '{': Expected '(' after 'catch'.\n  --> test.lox:1:15\n  |\n1 | try { } catch { print 1; }\n  |               ^\n"},
+	})
+}
@@ -3,37 +3,45 @@ package main
 import (
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Scanner helpers
 func getKeywordMap() map[string]int {
 	return map[string]int{
-		"and":    TOKEN_AND,
-		"class":  TOKEN_CLASS,
-		"else":   TOKEN_ELSE,
-		"false":  TOKEN_FALSE,
-		"for":    TOKEN_FOR,
-		"fun":    TOKEN_FUN,
-		"if":     TOKEN_IF,
-		"nil":    TOKEN_NIL,
-		"or":     TOKEN_OR,
-		"print":  TOKEN_PRINT,
-		"return": TOKEN_RETURN,
-		"super":  TOKEN_SUPER,
-		"this":   TOKEN_THIS,
-		"true":   TOKEN_TRUE,
-		"var":    TOKEN_VAR,
-		"while":  TOKEN_WHILE,
+		"and":      TOKEN_AND,
+		"break":    TOKEN_BREAK,
+		"catch":    TOKEN_CATCH,
+		"class":    TOKEN_CLASS,
+		"const":    TOKEN_CONST,
+		"continue": TOKEN_CONTINUE,
+		"else":     TOKEN_ELSE,
+		"export":   TOKEN_EXPORT,
+		"false":    TOKEN_FALSE,
+		"finally":  TOKEN_FINALLY,
+		"for":      TOKEN_FOR,
+		"fun":      TOKEN_FUN,
+		"if":       TOKEN_IF,
+		"import":   TOKEN_IMPORT,
+		"match":    TOKEN_MATCH,
+		"nil":      TOKEN_NIL,
+		"or":       TOKEN_OR,
+		"print":    TOKEN_PRINT,
+		"return":   TOKEN_RETURN,
+		"super":    TOKEN_SUPER,
+		"this":     TOKEN_THIS,
+		"throw":    TOKEN_THROW,
+		"true":     TOKEN_TRUE,
+		"try":      TOKEN_TRY,
+		"var":      TOKEN_VAR,
+		"while":    TOKEN_WHILE,
 	}
 }
 
-func reportErrorScan(line int, where int, message string) {
-	fmt.Printf("[line %d, col %d] Error: %s\n", line, where, message)
-	hadError = true
-}
-
 // Scanner
 type GloxScanner struct {
 	source        []rune
@@ -43,10 +51,10 @@ type GloxScanner struct {
 	line          int
 	lineStart     int
 	keywords      map[string]int
-	errorReporter func(line int, col int, message string)
+	errorReporter func(line int, col int, length int, message string)
 }
 
-func NewGloxScanner(source string, errorReporter func(line int, col int, message string)) GloxScanner {
+func NewGloxScanner(source string, errorReporter func(line int, col int, length int, message string)) GloxScanner {
 	return GloxScanner{
 		source:        []rune(source),
 		start:         0,
@@ -72,7 +80,7 @@ func (s *GloxScanner) scanToken() {
 	c := s.advance()
 	switch c {
 	case 0:
-		s.errorReporter(s.line, s.current-s.lineStart, "Unexpected end of file.")
+		s.errorReporter(s.line, s.start-s.lineStart, 1, "Unexpected end of file.")
 	case '\n':
 		s.line++
 		s.lineStart = s.current
@@ -89,6 +97,12 @@ func (s *GloxScanner) scanToken() {
 	case '}':
 		s.addToken(TOKEN_RIGHT_BRACE)
 		break
+	case '[':
+		s.addToken(TOKEN_LEFT_BRACKET)
+		break
+	case ']':
+		s.addToken(TOKEN_RIGHT_BRACKET)
+		break
 	case ',':
 		s.addToken(TOKEN_COMMA)
 		break
@@ -96,19 +110,65 @@ func (s *GloxScanner) scanToken() {
 		s.addToken(TOKEN_DOT)
 		break
 	case '-':
-		s.addToken(TOKEN_MINUS)
+		if s.match('-') {
+			s.addToken(TOKEN_MINUS_MINUS)
+		} else if s.match('=') {
+			s.addToken(TOKEN_MINUS_EQUAL)
+		} else {
+			s.addToken(TOKEN_MINUS)
+		}
 		break
 	case '+':
-		s.addToken(TOKEN_PLUS)
+		if s.match('+') {
+			s.addToken(TOKEN_PLUS_PLUS)
+		} else if s.match('=') {
+			s.addToken(TOKEN_PLUS_EQUAL)
+		} else {
+			s.addToken(TOKEN_PLUS)
+		}
 		break
 	case ';':
 		s.addToken(TOKEN_SEMICOLON)
 		break
 	case '*':
-		s.addToken(TOKEN_STAR)
+		if s.match('*') {
+			s.addToken(TOKEN_STAR_STAR)
+		} else if s.match('=') {
+			s.addToken(TOKEN_STAR_EQUAL)
+		} else {
+			s.addToken(TOKEN_STAR)
+		}
+		break
+	case '%':
+		s.addToken(TOKEN_PERCENT)
+		break
+	case '~':
+		// Floor division is spelled "~/" because "//" starts a comment.
+		if s.match('/') {
+			s.addToken(TOKEN_TILDE_SLASH)
+		} else {
+			s.addToken(TOKEN_TILDE)
+		}
+		break
+	case '&':
+		s.addToken(TOKEN_AMPERSAND)
+		break
+	case '|':
+		s.addToken(TOKEN_PIPE)
+		break
+	case '^':
+		s.addToken(TOKEN_CARET)
 		break
 	case '?':
-		s.addToken(TOKEN_QUESTION_MARK)
+		// An optional index is written "?.[" so that it can't be mistaken
+		// for a ternary such as c?[1]:[2].
+		if s.match('?') {
+			s.addToken(TOKEN_QUESTION_QUESTION)
+		} else if s.match('.') {
+			s.addToken(TOKEN_QUESTION_DOT)
+		} else {
+			s.addToken(TOKEN_QUESTION_MARK)
+		}
 		break
 	case ':':
 		s.addToken(TOKEN_COLON)
@@ -123,6 +183,8 @@ func (s *GloxScanner) scanToken() {
 	case '=':
 		if s.match('=') {
 			s.addToken(TOKEN_EQUAL_EQUAL)
+		} else if s.match('>') {
+			s.addToken(TOKEN_FAT_ARROW)
 		} else {
 			s.addToken(TOKEN_EQUAL)
 		}
@@ -130,6 +192,8 @@ func (s *GloxScanner) scanToken() {
 	case '<':
 		if s.match('=') {
 			s.addToken(TOKEN_LESS_EQUAL)
+		} else if s.match('<') {
+			s.addToken(TOKEN_LESS_LESS)
 		} else {
 			s.addToken(TOKEN_LESS)
 		}
@@ -137,6 +201,8 @@ func (s *GloxScanner) scanToken() {
 	case '>':
 		if s.match('=') {
 			s.addToken(TOKEN_GREATER_EQUAL)
+		} else if s.match('>') {
+			s.addToken(TOKEN_GREATER_GREATER)
 		} else {
 			s.addToken(TOKEN_GREATER)
 		}
@@ -146,6 +212,8 @@ func (s *GloxScanner) scanToken() {
 			s.singleLineComment()
 		} else if s.match('*') {
 			s.multiLineComment()
+		} else if s.match('=') {
+			s.addToken(TOKEN_SLASH_EQUAL)
 		} else {
 			s.addToken(TOKEN_SLASH)
 		}
@@ -155,15 +223,15 @@ func (s *GloxScanner) scanToken() {
 	case '"':
 		s.string()
 	default:
-		if unicode.IsDigit(c) {
+		if isDigit(c) {
 			s.number()
-		} else if unicode.IsLetter(c) {
+		} else if unicode.IsLetter(c) || c == '_' {
 			for unicode.IsLetter(s.peek()) || unicode.IsDigit(s.peek()) {
 				s.advance()
 			}
 			s.identifier()
 		} else {
-			s.errorReporter(s.line, s.current-s.lineStart, fmt.Sprintf("Unexpected character: %s.", string(s.peekPrev())))
+			s.errorReporter(s.line, s.start-s.lineStart, 1, fmt.Sprintf("Unexpected character: %s.", string(s.peekPrev())))
 		}
 	}
 }
@@ -223,6 +291,7 @@ func (s *GloxScanner) singleLineComment() {
 }
 
 func (s *GloxScanner) multiLineComment() {
+	startLine, startCol := s.line, s.start-s.lineStart
 	for s.peek() != '*' && s.peekNext() != '/' && !s.isAtEnd() {
 		if s.peek() == '\n' {
 			s.line++
@@ -231,7 +300,7 @@ func (s *GloxScanner) multiLineComment() {
 		s.advance()
 	}
 	if s.isAtEnd() {
-		s.errorReporter(s.line, s.start-s.lineStart, "Unterminated multi-line comment.")
+		s.errorReporter(startLine, startCol, 2, "Unterminated multi-line comment.")
 		return
 	}
 	// Consume the closing '*/'
@@ -240,40 +309,296 @@ func (s *GloxScanner) multiLineComment() {
 }
 
 func (s *GloxScanner) string() {
+	startLine, startCol := s.line, s.start-s.lineStart
+	var template StringTemplate
+	var part strings.Builder
 	for s.peek() != '"' && !s.isAtEnd() {
+		if s.peek() == '\\' {
+			s.escape(&part)
+			continue
+		}
+		if s.peek() == '$' && s.peekNext() == '{' {
+			template.parts = append(template.parts, part.String())
+			part.Reset()
+			holeLine, holeCol := s.line, s.current-s.lineStart
+			s.advance()
+			s.advance()
+			tokens, ok := s.interpolation()
+			if !ok {
+				s.errorReporter(holeLine, holeCol, 2, "Unterminated interpolation in string.")
+				return
+			}
+			template.exprs = append(template.exprs, tokens)
+			continue
+		}
 		if s.peek() == '\n' && !s.peekEnd() {
 			s.line++
+			s.lineStart = s.current + 1
 		}
-		s.advance()
+		part.WriteRune(s.advance())
 	}
 	if s.isAtEnd() {
-		s.errorReporter(s.line, s.start-s.lineStart, "Unterminated string.")
+		s.errorReporter(startLine, startCol, s.current-s.start, "Unterminated string.")
 		return
 	}
 	s.advance()
-	s.addTokenLiteral(TOKEN_STRING, string(s.source[s.start+1:s.current-1]))
+	if len(template.exprs) == 0 {
+		s.addTokenAt(TOKEN_STRING, part.String(), startLine, startCol)
+		return
+	}
+	template.parts = append(template.parts, part.String())
+	s.addTokenAt(TOKEN_INTERPOLATION, template, startLine, startCol)
 }
 
-func (s *GloxScanner) number() {
-	for unicode.IsDigit(s.peek()) && !s.isAtEnd() {
-		s.advance()
+// escape decodes the escape sequence starting at the backslash under the
+// cursor and writes the character it stands for to part. Bad escapes are
+// reported at their own column and scanning carries on after them.
+func (s *GloxScanner) escape(part *strings.Builder) {
+	start := s.current
+	col := start - s.lineStart
+	s.advance()
+	if s.isAtEnd() {
+		return
+	}
+
+	c := s.advance()
+	switch c {
+	case 'n':
+		part.WriteRune('\n')
+	case 't':
+		part.WriteRune('\t')
+	case 'r':
+		part.WriteRune('\r')
+	case '0':
+		part.WriteRune(0)
+	case '\\', '"', '$':
+		part.WriteRune(c)
+	case 'x':
+		digits := s.hexDigits(2)
+		if len(digits) != 2 {
+			s.errorReporter(s.line, col, s.current-start, "Expected two hex digits after '\\x'.")
+			return
+		}
+		value, _ := strconv.ParseUint(digits, 16, 8)
+		part.WriteRune(rune(value))
+	case 'u':
+		if !s.match('{') {
+			s.errorReporter(s.line, col, s.current-start, "Expected '{' after '\\u'.")
+			return
+		}
+		digits := s.hexDigits(6)
+		if len(digits) == 0 || !s.match('}') {
+			s.errorReporter(s.line, col, s.current-start, "Expected one to six hex digits and '}' in '\\u{...}'.")
+			return
+		}
+		value, _ := strconv.ParseUint(digits, 16, 32)
+		if !utf8.ValidRune(rune(value)) {
+			s.errorReporter(s.line, col, s.current-start, fmt.Sprintf("Invalid Unicode code point U+%s.", strings.ToUpper(digits)))
+			return
+		}
+		part.WriteRune(rune(value))
+	default:
+		if c == '\n' {
+			s.line++
+			s.lineStart = s.current
+			s.errorReporter(s.line-1, col, 1, "Invalid escape sequence at end of line.")
+			return
+		}
+		s.errorReporter(s.line, col, 2, fmt.Sprintf("Invalid escape sequence '\\%s'.", string(c)))
 	}
+}
 
-	if s.peek() == '.' && unicode.IsDigit(s.peekNext()) {
+// hexDigits consumes up to limit hex digits and returns them.
+func (s *GloxScanner) hexDigits(limit int) string {
+	start := s.current
+	for s.current-start < limit && isHexDigit(s.peek()) {
 		s.advance()
 	}
-	for unicode.IsDigit(s.peek()) {
+	return string(s.source[start:s.current])
+}
+
+func isHexDigit(r rune) bool {
+	return isDigitIn(r, 16)
+}
+
+// interpolation scans the expression inside a "${...}" hole, starting just
+// after the "${", and stops at the '}' that closes the hole. The tokens keep
+// their real positions in the source so errors point into the string. The
+// closing brace is replaced by an EOF token for the parser.
+func (s *GloxScanner) interpolation() ([]Token, bool) {
+	sub := NewGloxScanner("", s.errorReporter)
+	sub.source = s.source
+	sub.current = s.current
+	sub.line, sub.lineStart = s.line, s.lineStart
+
+	depth := 0
+	for !sub.isAtEnd() {
+		scanned := len(sub.tokens)
+		sub.scanToken()
+		if len(sub.tokens) == scanned {
+			continue
+		}
+
+		last := &sub.tokens[len(sub.tokens)-1]
+		switch last.tokenType {
+		case TOKEN_LEFT_BRACE:
+			depth++
+		case TOKEN_RIGHT_BRACE:
+			if depth == 0 {
+				last.tokenType = TOKEN_EOF
+				s.current = sub.current
+				s.line, s.lineStart = sub.line, sub.lineStart
+				return sub.tokens, true
+			}
+			depth--
+		}
+	}
+
+	s.current = sub.current
+	return nil, false
+}
+
+func (s *GloxScanner) number() {
+	if s.peekPrev() == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			s.radixNumber(16, "hexadecimal")
+			return
+		case 'b', 'B':
+			s.radixNumber(2, "binary")
+			return
+		case 'o', 'O':
+			s.radixNumber(8, "octal")
+			return
+		}
+	}
+
+	s.decimalDigits()
+	if s.peek() == '.' && isDigit(s.peekNext()) {
 		s.advance()
+		s.decimalDigits()
+	}
+	if s.peek() == 'e' || s.peek() == 'E' {
+		next := s.peekNext()
+		if (next == '+' || next == '-') && s.current+2 < len(s.source) && isDigit(s.source[s.current+2]) {
+			s.advance()
+			s.advance()
+			s.decimalDigits()
+		} else if isDigit(next) {
+			s.advance()
+			s.decimalDigits()
+		}
+	}
+	if s.runOn() {
+		s.errorReporter(s.line, s.start-s.lineStart, s.current-s.start, fmt.Sprintf("Malformed number literal '%s'.", string(s.source[s.start:s.current])))
+		return
 	}
 
-	num, err := strconv.ParseFloat(string(s.source[s.start:s.current]), 64)
-	// If we can't parse the number, panic. This should never happen.
+	text, ok := s.numberText(s.start, isDigit)
+	if !ok {
+		return
+	}
+
+	// Without a fraction or an exponent the literal is an integer.
+	if !strings.ContainsAny(text, ".eE") {
+		s.addTokenLiteral(TOKEN_NUMBER, parseInteger(text, 10))
+		return
+	}
+
+	num, err := strconv.ParseFloat(text, 64)
 	if err != nil {
-		panic(err)
+		s.errorReporter(s.line, s.start-s.lineStart, s.current-s.start, "Number literal is out of range.")
+		return
 	}
 	s.addTokenLiteral(TOKEN_NUMBER, num)
 }
 
+// radixNumber scans a literal such as 0xFF, 0b1010 or 0o17. The cursor is
+// on the letter after the leading zero.
+func (s *GloxScanner) radixNumber(base int, name string) {
+	s.advance()
+	bodyStart := s.current
+	s.runOn()
+
+	if s.current == bodyStart {
+		s.errorReporter(s.line, s.start-s.lineStart, s.current-s.start, fmt.Sprintf("Expected digits after '%s'.", string(s.source[s.start:s.current])))
+		return
+	}
+	for i := bodyStart; i < s.current; i++ {
+		if c := s.source[i]; c != '_' && !isDigitIn(c, base) {
+			s.errorReporter(s.line, i-s.lineStart, 1, fmt.Sprintf("Invalid digit '%s' in %s literal.", string(c), name))
+			return
+		}
+	}
+
+	text, ok := s.numberText(bodyStart, func(r rune) bool { return isDigitIn(r, base) })
+	if !ok {
+		return
+	}
+	s.addTokenLiteral(TOKEN_NUMBER, parseInteger(text, base))
+}
+
+// parseInteger converts digits already checked by the scanner to an int64,
+// or to a *big.Int when they don't fit in one.
+func parseInteger(digits string, base int) any {
+	if num, err := strconv.ParseInt(digits, base, 64); err == nil {
+		return num
+	}
+	num, _ := new(big.Int).SetString(digits, base)
+	return num
+}
+
+func (s *GloxScanner) decimalDigits() {
+	for isDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// runOn consumes any letters, digits and '_' that follow a number, so that
+// 12abc or 0b102 is reported as one bad literal rather than splitting into
+// a number and an identifier. It reports whether there were any.
+func (s *GloxScanner) runOn() bool {
+	start := s.current
+	for c := s.peek(); unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'; c = s.peek() {
+		s.advance()
+	}
+	return s.current > start
+}
+
+// numberText returns the digits of the number just scanned, from index from
+// of the source, with their '_' separators removed. Each separator must sit
+// between two digits.
+func (s *GloxScanner) numberText(from int, digit func(rune) bool) (string, bool) {
+	text := s.source[from:s.current]
+	for i, c := range text {
+		if c != '_' {
+			continue
+		}
+		if i == 0 || i == len(text)-1 || !digit(text[i-1]) || !digit(text[i+1]) {
+			s.errorReporter(s.line, from+i-s.lineStart, 1, "Digit separator '_' must be between two digits.")
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(string(text), "_", ""), true
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// isDigitIn reports whether r is a digit in the given base, up to 16.
+func isDigitIn(r rune, base int) bool {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r-'0') < base
+	case 'a' <= r && r <= 'f':
+		return int(r-'a'+10) < base
+	case 'A' <= r && r <= 'F':
+		return int(r-'A'+10) < base
+	}
+	return false
+}
+
 func (s *GloxScanner) identifier() {
 	for unicode.IsLetter(s.peek()) || unicode.IsDigit(s.peek()) || s.peek() == rune('_') {
 		s.advance()
@@ -299,6 +624,12 @@ func (s *GloxScanner) addToken(tokenType int) {
 }
 
 func (s *GloxScanner) addTokenLiteral(tokenType int, literal any) {
+	s.addTokenAt(tokenType, literal, s.line, s.start-s.lineStart)
+}
+
+// addTokenAt adds a token that starts at the given position rather than on
+// the current line, for tokens such as strings that can span several lines.
+func (s *GloxScanner) addTokenAt(tokenType int, literal any, line int, col int) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, NewToken(tokenType, string(text), literal, s.line, s.start-s.lineStart))
+	s.tokens = append(s.tokens, NewToken(tokenType, string(text), literal, line, col))
 }
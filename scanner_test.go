@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func TestInterpolation(t *testing.T) {
+	runCases(t, []loxCase{
+		{"variable", `var name = "ann"; print "Hello ${name}!";`, "Hello ann!\n"},
+		{"expression", `var n = 2; print "${n + 1} items";`, "3 items\n"},
+		{"numbers and nil are stringified", `print "${1} ${nil} ${true} ${[1, 2]}";`,
+			"1 nil true [1, 2]\n"},
+		{"only a hole", `print "${"inner"}";`, "inner\n"},
+		{"nested strings and holes", `var a = "x"; print "out ${"in ${a}"} out";`, "out in x out\n"},
+		{"map literal inside a hole", `print "${{"k": 1}["k"]}";`, "1\n"},
+		{"dollar without a brace", `print "cost: $5";`, "cost: $5\n"},
+		{"plain strings are unchanged", `print "no holes";`, "no holes\n"},
+		{"runtime error points into the string", `print "a ${missing} b";`,
+			"error: Undefined variable : missing\n  --> test.lox:1:12\n  |\n1 | print \"a ${missing} b\";\n  |            ^~~~~~~\n"},
+		{"syntax error inside a hole", `print "a ${1 +} b";`,
+			"error: at end: Expected expression.\n  --> test.lox:1:15\n  |\n1 | print \"a ${1 +} b\";\n  |               ^\n"},
+		{"unterminated hole", `print "a ${1 + 2";`,
+			"error: Unterminated string.\n  --> test.lox:1:17\n  |\n1 | print \"a ${1 + 2\";\n  |                 ^~\n" +
+				"error: Unterminated interpolation in string.\n  --> test.lox:1:10\n  |\n1 | print \"a ${1 + 2\";\n  |          ^~\n"},
+		{"hole on a later line of the string", "print \"one\n${nope}\";",
+			"error: Undefined variable : nope\n  --> test.lox:2:3\n  |\n2 | ${nope}\";\n  |   ^~~~\n"},
+		{"syntax error in a hole on a later line", "print \"one\n${1 +}\";",
+			"error: at end: Expected expression.\n  --> test.lox:2:6\n  |\n2 | ${1 +}\";\n  |      ^\n"},
+		{"unterminated hole on a later line", "print \"one\n  ${1 + 2\";",
+			"error: Unterminated string.\n  --> test.lox:2:10\n  |\n2 |   ${1 + 2\";\n  |          ^~\n" +
+				"error: Unterminated interpolation in string.\n  --> test.lox:2:3\n  |\n2 |   ${1 + 2\";\n  |   ^~\n"},
+		{"newline inside a hole", "print \"${\n nope}\";",
+			"error: Undefined variable : nope\n  --> test.lox:2:2\n  |\n2 |  nope}\";\n  |  ^~~~\n"},
+		{"empty hole", `print "a ${} b";`,
+			"error: at end: Expected expression.\n  --> test.lox:1:12\n  |\n1 | print \"a ${} b\";\n  |            ^\n"},
+	})
+}
+
+func TestEscapes(t *testing.T) {
+	runCases(t, []loxCase{
+		{"newline and tab", `print "a\tb\nc";`, "a\tb\nc\n"},
+		{"quote and backslash", `print "say \"hi\" \\ bye";`, "say \"hi\" \\ bye\n"},
+		{"carriage return and nul", `print len("\r\0");`, "2\n"},
+		{"hex", `print "\x41\x62";`, "Ab\n"},
+		{"unicode", `print "\u{48}\u{e9}\u{1F600}";`, "Hé😀\n"},
+		{"escaped quotes inside a hole", `print "${"\"q\""}";`, "\"q\"\n"},
+		{"invalid escape", `print "a\qb";`,
+			"error: Invalid escape sequence '\\q'.\n  --> test.lox:1:9\n  |\n1 | print \"a\\qb\";\n  |         ^~\n"},
+		{"every bad escape is reported", `print "\q\x4";`,
+			"error: Invalid escape sequence '\\q'.\n  --> test.lox:1:8\n  |\n1 | print \"\\q\\x4\";\n  |        ^~\n" +
+				"error: Expected two hex digits after '\\x'.\n  --> test.lox:1:10\n  |\n1 | print \"\\q\\x4\";\n  |          ^~~\n"},
+		{"bad escape on a later line", "print 1;\nprint \"\\z\";",
+			"error: Invalid escape sequence '\\z'.\n  --> test.lox:2:8\n  |\n2 | print \"\\z\";\n  |        ^~\n"},
+		{"bad escape on the second line of a string", "print \"ab\ncd\\q\";",
+			"error: Invalid escape sequence '\\q'.\n  --> test.lox:2:3\n  |\n2 | cd\\q\";\n  |   ^~\n"},
+		{"columns after a multi-line string", "print \"ab\ncd\" + nope;",
+			"error: Undefined variable : nope\n  --> test.lox:2:7\n  |\n2 | cd\" + nope;\n  |       ^~~~\n"},
+		{"short hex escape", `print "\xG1";`,
+			"error: Expected two hex digits after '\\x'.\n  --> test.lox:1:8\n  |\n1 | print \"\\xG1\";\n  |        ^~\n"},
+		{"unicode without braces", `print "\u41";`,
+			"error: Expected '{' after '\\u'.\n  --> test.lox:1:8\n  |\n1 | print \"\\u41\";\n  |        ^~\n"},
+		{"unicode without digits", `print "\u{}";`,
+			"error: Expected one to six hex digits and '}' in '\\u{...}'.\n  --> test.lox:1:8\n  |\n1 | print \"\\u{}\";\n  |        ^~~\n"},
+		{"unicode too long", `print "\u{1234567}";`,
+			"error: Expected one to six hex digits and '}' in '\\u{...}'.\n  --> test.lox:1:8\n  |\n1 | print \"\\u{1234567}\";\n  |        ^~~~~~~~~\n"},
+		{"unicode out of range", `print "\u{110000}";`,
+			"error: Invalid Unicode code point U+110000.\n  --> test.lox:1:8\n  |\n1 | print \"\\u{110000}\";\n  |        ^~~~~~~~~~\n"},
+		{"surrogate", `print "\u{D800}";`,
+			"error: Invalid Unicode code point U+D800.\n  --> test.lox:1:8\n  |\n1 | print \"\\u{D800}\";\n  |        ^~~~~~~~\n"},
+	})
+}
+
+func TestEscapedLiteral(t *testing.T) {
+	scanner := NewGloxScanner(`"a\tb"`, func(line int, col int, length int, message string) {
+		t.Errorf("unexpected scan error at %d:%d: %s", line, col, message)
+	})
+	tokens := scanner.ScanTokens()
+
+	if tokens[0].lexeme != `"a\tb"` {
+		t.Errorf("lexeme = %q; want the raw source", tokens[0].lexeme)
+	}
+	if tokens[0].literal != "a\tb" {
+		t.Errorf("literal = %q; want the decoded string", tokens[0].literal)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	runCases(t, []loxCase{
+		{"hex", `print 0xFF + 0Xa;`, "265\n"},
+		{"binary", `print 0b1010;`, "10\n"},
+		{"octal", `print 0o17;`, "15\n"},
+		{"exponent", `print 1e3; print 2.5e-1;`, "1000.0\n0.25\n"},
+		{"capital exponent with sign", `print 6.02E+23;`, "6.02e+23\n"},
+		{"separators", `print 1_000 + 0xF_F;`, "1255\n"},
+		{"separator in a fraction", `print 1_0.2_5;`, "10.25\n"},
+		{"prefix without digits", `print 0x;`,
+			"error: Expected digits after '0x'.\n  --> test.lox:1:7\n  |\n1 | print 0x;\n  |       ^~\n"},
+		{"invalid binary digit", `print 0b102;`,
+			"error: Invalid digit '2' in binary literal.\n  --> test.lox:1:11\n  |\n1 | print 0b102;\n  |           ^\n"},
+		{"invalid hex digit", `print 0xFG;`,
+			"error: Invalid digit 'G' in hexadecimal literal.\n  --> test.lox:1:10\n  |\n1 | print 0xFG;\n  |          ^\n"},
+		{"letters after digits", `print 12abc;`,
+			"error: Malformed number literal '12abc'.\n  --> test.lox:1:7\n  |\n1 | print 12abc;\n  |       ^~~~~\n"},
+		{"missing exponent", `print 1e;`,
+			"error: Malformed number literal '1e'.\n  --> test.lox:1:7\n  |\n1 | print 1e;\n  |       ^~\n"},
+		{"trailing separator", `print 1_;`,
+			"error: Digit separator '_' must be between two digits.\n  --> test.lox:1:8\n  |\n1 | print 1_;\n  |        ^\n"},
+		{"doubled separator", `print 1__0;`,
+			"error: Digit separator '_' must be between two digits.\n  --> test.lox:1:8\n  |\n1 | print 1__0;\n  |        ^\n"},
+		{"separator after the prefix", `print 0x_F;`,
+			"error: Digit separator '_' must be between two digits.\n  --> test.lox:1:9\n  |\n1 | print 0x_F;\n  |         ^\n"},
+		{"out of range", `print 1e999;`,
+			"error: Number literal is out of range.\n  --> test.lox:1:7\n  |\n1 | print 1e999;\n  |       ^~~~~\n"},
+	})
+}
@@ -5,18 +5,34 @@ type StatementVisitor interface {
 	visitPrintStatement(stmt PrintStatement) (any, error)
 	visitVarDeclarationStatement(stmt VarDeclarationStatement) (any, error)
 	visitBlockStatement(stmt BlockStatement) (any, error)
+	visitIfStatement(stmt IfStatement) (any, error)
+	visitWhileStatement(stmt WhileStatement) (any, error)
+	visitForStatement(stmt ForStatement) (any, error)
+	visitBreakStatement(stmt BreakStatement) (any, error)
+	visitContinueStatement(stmt ContinueStatement) (any, error)
+	visitFunctionStatement(stmt FunctionStatement) (any, error)
+	visitReturnStatement(stmt ReturnStatement) (any, error)
+	visitClassStatement(stmt ClassStatement) (any, error)
+	visitThrowStatement(stmt ThrowStatement) (any, error)
+	visitTryStatement(stmt TryStatement) (any, error)
+	visitImportStatement(stmt ImportStatement) (any, error)
+	visitExportStatement(stmt ExportStatement) (any, error)
 }
 
 type Statement interface {
 	accept(visitor StatementVisitor) (any, error)
 }
 
+// VarDeclarationStatement declares a variable with var, or a constant with
+// const when constant is set. Constants always have an initializer.
 type VarDeclarationStatement struct {
 	name        Token
 	initializer Expr
+	constant    bool
 }
 
 type BlockStatement struct {
+	brace Token
 	stmts []Statement
 }
 
@@ -43,3 +59,133 @@ type PrintStatement struct {
 func (p PrintStatement) accept(visitor StatementVisitor) (any, error) {
 	return visitor.visitPrintStatement(p)
 }
+
+type IfStatement struct {
+	condition  Expr
+	thenBranch Statement
+	elseBranch Statement
+}
+
+func (i IfStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitIfStatement(i)
+}
+
+type WhileStatement struct {
+	label     *Token
+	condition Expr
+	body      Statement
+}
+
+func (w WhileStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitWhileStatement(w)
+}
+
+type ForStatement struct {
+	label       *Token
+	initializer Statement
+	condition   Expr
+	increment   Expr
+	body        Statement
+}
+
+func (f ForStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitForStatement(f)
+}
+
+type BreakStatement struct {
+	keyword Token
+	label   *Token
+}
+
+func (b BreakStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitBreakStatement(b)
+}
+
+type ContinueStatement struct {
+	keyword Token
+	label   *Token
+}
+
+func (c ContinueStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitContinueStatement(c)
+}
+
+type FunctionStatement struct {
+	name   Token
+	params []Token
+	body   []Statement
+}
+
+func (f FunctionStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitFunctionStatement(f)
+}
+
+type ReturnStatement struct {
+	keyword Token
+	value   Expr
+}
+
+func (r ReturnStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitReturnStatement(r)
+}
+
+type ClassStatement struct {
+	name       Token
+	superclass *Variable
+	methods    []FunctionStatement
+}
+
+func (c ClassStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitClassStatement(c)
+}
+
+type ThrowStatement struct {
+	keyword Token
+	value   Expr
+}
+
+func (t ThrowStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitThrowStatement(t)
+}
+
+// TryStatement has a catch clause, a finally clause or both. Without a catch
+// clause catchName and catchBody are nil, and without a finally clause
+// finallyBody is nil.
+type TryStatement struct {
+	keyword     Token
+	body        BlockStatement
+	catchName   *Token
+	catchBody   *BlockStatement
+	finallyBody *BlockStatement
+}
+
+func (t TryStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitTryStatement(t)
+}
+
+// ImportStatement is either import "path" as alias; or
+// import {names} from "path";. A bare import "path"; only runs the module.
+// Either way the module's exports are read live, and imported names can't be
+// assigned to.
+type ImportStatement struct {
+	keyword Token
+	path    Token
+	alias   *Token
+	names   []Token
+}
+
+func (i ImportStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitImportStatement(i)
+}
+
+// ExportStatement wraps a top-level declaration whose name other modules can
+// import.
+type ExportStatement struct {
+	keyword     Token
+	name        Token
+	declaration Statement
+}
+
+func (e ExportStatement) accept(visitor StatementVisitor) (any, error) {
+	return visitor.visitExportStatement(e)
+}
@@ -14,6 +14,14 @@ func NewToken(tokenType int, lexeme string, literal any, line int, col int) Toke
 	return Token{tokenType: tokenType, lexeme: lexeme, literal: literal, line: line, col: col}
 }
 
+// StringTemplate is the literal of a TOKEN_INTERPOLATION: the text of a
+// string split around its "${...}" holes, with the tokens of the expression
+// in each hole. There is always one more part than there are expressions.
+type StringTemplate struct {
+	parts []string
+	exprs [][]Token
+}
+
 func (t Token) String() string {
 	return fmt.Sprintf("Token(%s, \"%s\", %v)", t.tokenTypeString(), t.lexeme, t.literal)
 }
@@ -28,6 +36,10 @@ func (t *Token) tokenTypeString() string {
 		return "TOKEN_LEFT_BRACE"
 	case TOKEN_RIGHT_BRACE:
 		return "TOKEN_RIGHT_BRACE"
+	case TOKEN_LEFT_BRACKET:
+		return "TOKEN_LEFT_BRACKET"
+	case TOKEN_RIGHT_BRACKET:
+		return "TOKEN_RIGHT_BRACKET"
 	case TOKEN_COMMA:
 		return "TOKEN_COMMA"
 	case TOKEN_DOT:
@@ -42,6 +54,16 @@ func (t *Token) tokenTypeString() string {
 		return "TOKEN_SLASH"
 	case TOKEN_STAR:
 		return "TOKEN_STAR"
+	case TOKEN_PERCENT:
+		return "TOKEN_PERCENT"
+	case TOKEN_AMPERSAND:
+		return "TOKEN_AMPERSAND"
+	case TOKEN_PIPE:
+		return "TOKEN_PIPE"
+	case TOKEN_CARET:
+		return "TOKEN_CARET"
+	case TOKEN_TILDE:
+		return "TOKEN_TILDE"
 	case TOKEN_QUESTION_MARK:
 		return "TOKEN_QUESTION_MARK"
 	case TOKEN_COLON:
@@ -54,6 +76,8 @@ func (t *Token) tokenTypeString() string {
 		return "TOKEN_EQUAL"
 	case TOKEN_EQUAL_EQUAL:
 		return "TOKEN_EQUAL_EQUAL"
+	case TOKEN_FAT_ARROW:
+		return "TOKEN_FAT_ARROW"
 	case TOKEN_GREATER:
 		return "TOKEN_GREATER"
 	case TOKEN_GREATER_EQUAL:
@@ -62,26 +86,68 @@ func (t *Token) tokenTypeString() string {
 		return "TOKEN_LESS"
 	case TOKEN_LESS_EQUAL:
 		return "TOKEN_LESS_EQUAL"
+	case TOKEN_STAR_STAR:
+		return "TOKEN_STAR_STAR"
+	case TOKEN_QUESTION_QUESTION:
+		return "TOKEN_QUESTION_QUESTION"
+	case TOKEN_QUESTION_DOT:
+		return "TOKEN_QUESTION_DOT"
+	case TOKEN_LESS_LESS:
+		return "TOKEN_LESS_LESS"
+	case TOKEN_GREATER_GREATER:
+		return "TOKEN_GREATER_GREATER"
+	case TOKEN_PLUS_EQUAL:
+		return "TOKEN_PLUS_EQUAL"
+	case TOKEN_MINUS_EQUAL:
+		return "TOKEN_MINUS_EQUAL"
+	case TOKEN_STAR_EQUAL:
+		return "TOKEN_STAR_EQUAL"
+	case TOKEN_SLASH_EQUAL:
+		return "TOKEN_SLASH_EQUAL"
+	case TOKEN_PLUS_PLUS:
+		return "TOKEN_PLUS_PLUS"
+	case TOKEN_MINUS_MINUS:
+		return "TOKEN_MINUS_MINUS"
+	case TOKEN_TILDE_SLASH:
+		return "TOKEN_TILDE_SLASH"
 	case TOKEN_IDENTIFIER:
 		return "TOKEN_IDENTIFIER"
 	case TOKEN_STRING:
 		return "TOKEN_STRING"
+	case TOKEN_INTERPOLATION:
+		return "TOKEN_INTERPOLATION"
 	case TOKEN_NUMBER:
 		return "TOKEN_NUMBER"
 	case TOKEN_AND:
 		return "TOKEN_AND"
+	case TOKEN_BREAK:
+		return "TOKEN_BREAK"
 	case TOKEN_CLASS:
 		return "TOKEN_CLASS"
+	case TOKEN_CONST:
+		return "TOKEN_CONST"
+	case TOKEN_CATCH:
+		return "TOKEN_CATCH"
+	case TOKEN_CONTINUE:
+		return "TOKEN_CONTINUE"
 	case TOKEN_ELSE:
 		return "TOKEN_ELSE"
+	case TOKEN_EXPORT:
+		return "TOKEN_EXPORT"
 	case TOKEN_FALSE:
 		return "TOKEN_FALSE"
+	case TOKEN_FINALLY:
+		return "TOKEN_FINALLY"
 	case TOKEN_FUN:
 		return "TOKEN_FUN"
 	case TOKEN_FOR:
 		return "TOKEN_FOR"
 	case TOKEN_IF:
 		return "TOKEN_IF"
+	case TOKEN_IMPORT:
+		return "TOKEN_IMPORT"
+	case TOKEN_MATCH:
+		return "TOKEN_MATCH"
 	case TOKEN_NIL:
 		return "TOKEN_NIL"
 	case TOKEN_OR:
@@ -94,8 +160,12 @@ func (t *Token) tokenTypeString() string {
 		return "TOKEN_SUPER"
 	case TOKEN_THIS:
 		return "TOKEN_THIS"
+	case TOKEN_THROW:
+		return "TOKEN_THROW"
 	case TOKEN_TRUE:
 		return "TOKEN_TRUE"
+	case TOKEN_TRY:
+		return "TOKEN_TRY"
 	case TOKEN_VAR:
 		return "TOKEN_VAR"
 	case TOKEN_WHILE:
@@ -114,6 +184,8 @@ const (
 	TOKEN_RIGHT_PAREN
 	TOKEN_LEFT_BRACE
 	TOKEN_RIGHT_BRACE
+	TOKEN_LEFT_BRACKET
+	TOKEN_RIGHT_BRACKET
 	TOKEN_COMMA
 	TOKEN_DOT
 	TOKEN_MINUS
@@ -121,6 +193,11 @@ const (
 	TOKEN_SEMICOLON
 	TOKEN_SLASH
 	TOKEN_STAR
+	TOKEN_PERCENT
+	TOKEN_AMPERSAND
+	TOKEN_PIPE
+	TOKEN_CARET
+	TOKEN_TILDE
 	TOKEN_QUESTION_MARK
 	TOKEN_COLON
 
@@ -129,31 +206,55 @@ const (
 	TOKEN_BANG_EQUAL
 	TOKEN_EQUAL
 	TOKEN_EQUAL_EQUAL
+	TOKEN_FAT_ARROW
 	TOKEN_GREATER
 	TOKEN_GREATER_EQUAL
 	TOKEN_LESS
 	TOKEN_LESS_EQUAL
+	TOKEN_STAR_STAR
+	TOKEN_QUESTION_QUESTION
+	TOKEN_QUESTION_DOT
+	TOKEN_LESS_LESS
+	TOKEN_GREATER_GREATER
+	TOKEN_PLUS_EQUAL
+	TOKEN_MINUS_EQUAL
+	TOKEN_STAR_EQUAL
+	TOKEN_SLASH_EQUAL
+	TOKEN_PLUS_PLUS
+	TOKEN_MINUS_MINUS
+	TOKEN_TILDE_SLASH
 
 	// Literals.
 	TOKEN_IDENTIFIER
 	TOKEN_STRING
+	TOKEN_INTERPOLATION
 	TOKEN_NUMBER
 
 	// Keywords.
 	TOKEN_AND
+	TOKEN_BREAK
 	TOKEN_CLASS
+	TOKEN_CONST
+	TOKEN_CATCH
+	TOKEN_CONTINUE
 	TOKEN_ELSE
+	TOKEN_EXPORT
 	TOKEN_FALSE
+	TOKEN_FINALLY
 	TOKEN_FUN
 	TOKEN_FOR
 	TOKEN_IF
+	TOKEN_IMPORT
+	TOKEN_MATCH
 	TOKEN_NIL
 	TOKEN_OR
 	TOKEN_PRINT
 	TOKEN_RETURN
 	TOKEN_SUPER
 	TOKEN_THIS
+	TOKEN_THROW
 	TOKEN_TRUE
+	TOKEN_TRY
 	TOKEN_VAR
 	TOKEN_WHILE
 